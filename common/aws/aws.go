@@ -1,7 +1,15 @@
 package aws
 
-import "github.com/gfa-inc/gfa/common/aws/s3x"
+import (
+	"context"
+
+	"github.com/gfa-inc/gfa/common/aws/s3x"
+)
 
 func Setup() {
 	s3x.Setup()
 }
+
+func Ping(ctx context.Context) error {
+	return s3x.Ping(ctx)
+}
@@ -2,8 +2,11 @@ package s3x
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -15,6 +18,7 @@ import (
 var (
 	Client     *s3.Client
 	clientPool map[string]*s3.Client
+	bucketPool map[string]string
 )
 
 type Config struct {
@@ -47,6 +51,7 @@ func NewS3Client(option Config) (*s3.Client, error) {
 
 func Setup() {
 	clientPool = make(map[string]*s3.Client)
+	bucketPool = make(map[string]string)
 
 	if config.Get("aws.s3") == nil {
 		logger.Debug("No aws s3 config found")
@@ -70,6 +75,7 @@ func Setup() {
 			continue
 		}
 		PutClient(k, client)
+		bucketPool[k] = v.Bucket
 
 		if v.Default {
 			Client = client
@@ -91,3 +97,21 @@ func GetClient(name string) *s3.Client {
 func PutClient(name string, client *s3.Client) {
 	clientPool[name] = client
 }
+
+// Ping checks the configured bucket of every client in the pool,
+// falling back to listing buckets when no bucket is configured
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		var err error
+		if bucket := bucketPool[name]; bucket != "" {
+			_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+		} else {
+			_, err = client.ListBuckets(ctx, &s3.ListBucketsInput{MaxBuckets: aws.Int32(1)})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("aws s3 [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -16,6 +16,14 @@ func Setup() {
 	redisx.Setup()
 }
 
+func Ping(ctx context.Context) error {
+	return redisx.Ping(ctx)
+}
+
+func Close() error {
+	return redisx.Close()
+}
+
 func Key[T any](ctx context.Context, prefix string, value T) (string, error) {
 	key, err := hash.Hash(ctx, value)
 	if err != nil {
@@ -2,10 +2,13 @@ package redisx
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/metrics"
 	"github.com/redis/go-redis/v9"
 	"github.com/samber/lo"
 )
@@ -29,6 +32,9 @@ func NewClient(option Config) (redis.UniversalClient, error) {
 		Username: option.Username,
 		Password: option.Password,
 	})
+	if metrics.Enabled() {
+		client.AddHook(metrics.NewRedisHook(option.Name))
+	}
 	err := client.Ping(context.Background()).Err()
 	if err != nil {
 		logger.Panic(err)
@@ -81,3 +87,26 @@ func GetClient(name string) redis.UniversalClient {
 func PutClient(name string, client redis.UniversalClient) {
 	clientPool[name] = client
 }
+
+// Ping pings every client in the pool
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		if err := client.Ping(ctx).Err(); err != nil {
+			errs = append(errs, fmt.Errorf("redis [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every client in the pool
+func Close() error {
+	var errs []error
+	for name, client := range clientPool {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis [%s]: %w", name, err))
+		}
+	}
+	logger.Infof("Redis client pool has been closed")
+	return errors.Join(errs...)
+}
@@ -1,10 +1,14 @@
 package casbinx
 
 import (
+	"context"
+	"errors"
+
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/util"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/gfa-inc/gfa"
 	"github.com/gfa-inc/gfa/common/db/mysqlx"
 	"github.com/gfa-inc/gfa/common/logger"
 	"github.com/gfa-inc/gfa/resources"
@@ -38,6 +42,37 @@ func Setup() {
 	}
 }
 
+// Component wraps Setup as a lifecycle component that starts after the db component
+func Component() gfa.Component {
+	return &component{}
+}
+
+type component struct{}
+
+func (*component) Name() string {
+	return "casbin"
+}
+
+func (*component) DependsOn() []string {
+	return []string{gfa.ComponentDB}
+}
+
+func (*component) Start(_ context.Context) error {
+	Setup()
+	return nil
+}
+
+func (*component) Stop(_ context.Context) error {
+	return nil
+}
+
+func (*component) Health(_ context.Context) error {
+	if Enforcer == nil {
+		return errors.New("casbin enforcer not initialized")
+	}
+	return nil
+}
+
 func SetCasbinModelConf(mdl string) {
 	casbinModelConf = mdl
 }
@@ -1,7 +1,22 @@
 package db
 
-import "github.com/gfa-inc/gfa/common/db/mysqlx"
+import (
+	"context"
+	"errors"
+
+	"github.com/gfa-inc/gfa/common/db/mysqlx"
+	"github.com/gfa-inc/gfa/common/db/sqlx"
+)
 
 func Setup() {
 	mysqlx.Setup()
+	sqlx.Setup()
+}
+
+func Ping(ctx context.Context) error {
+	return errors.Join(mysqlx.Ping(ctx), sqlx.Ping(ctx))
+}
+
+func Close() error {
+	return errors.Join(mysqlx.Close(), sqlx.Close())
 }
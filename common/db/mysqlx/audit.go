@@ -0,0 +1,97 @@
+package mysqlx
+
+import (
+	"context"
+	"time"
+
+	"github.com/gfa-inc/gfa/utils/principal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// WithUser sets the user filling the audit columns, e.g. for jobs running without a request
+func WithUser(ctx context.Context, userID string) context.Context {
+	return principal.WithUserID(ctx, userID)
+}
+
+// UserID returns the user set by WithUser, or the user authenticated by the security middleware
+func UserID(ctx context.Context) string {
+	return principal.UserID(ctx)
+}
+
+// AuditPlugin fills the audit columns of the models having them, the time columns are left to gorm
+// if they are tagged autoCreateTime or autoUpdateTime
+type AuditPlugin struct {
+	CreateTime string
+	UpdateTime string
+	Creator    string
+	Updater    string
+}
+
+// NewAuditPlugin creates the plugin of the create_time, update_time, creator and updater columns
+func NewAuditPlugin() *AuditPlugin {
+	return &AuditPlugin{
+		CreateTime: "create_time",
+		UpdateTime: "update_time",
+		Creator:    "creator",
+		Updater:    "updater",
+	}
+}
+
+func (p *AuditPlugin) Name() string {
+	return "gfa:audit"
+}
+
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	err := db.Callback().Create().Before("gorm:create").Register("gfa:audit", p.beforeCreate)
+	if err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("gfa:audit", p.beforeUpdate)
+}
+
+func (p *AuditPlugin) beforeCreate(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+
+	now := time.Now()
+	p.setTime(db, p.CreateTime, now)
+	p.setTime(db, p.UpdateTime, now)
+	if userID := UserID(db.Statement.Context); userID != "" {
+		p.set(db, p.Creator, userID)
+		p.set(db, p.Updater, userID)
+	}
+}
+
+func (p *AuditPlugin) beforeUpdate(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+
+	p.setTime(db, p.UpdateTime, time.Now())
+	if userID := UserID(db.Statement.Context); userID != "" {
+		p.set(db, p.Updater, userID)
+	}
+}
+
+func (p *AuditPlugin) setTime(db *gorm.DB, column string, now time.Time) {
+	field := db.Statement.Schema.LookUpField(column)
+	if field == nil || field.AutoCreateTime != 0 || field.AutoUpdateTime != 0 {
+		return
+	}
+	db.Statement.SetColumn(field.DBName, now, true)
+}
+
+func (p *AuditPlugin) set(db *gorm.DB, column string, value any) {
+	if field := lookUpField(db.Statement.Schema, column); field != nil {
+		db.Statement.SetColumn(field.DBName, value, true)
+	}
+}
+
+func lookUpField(s *schema.Schema, column string) *schema.Field {
+	if column == "" {
+		return nil
+	}
+	return s.LookUpField(column)
+}
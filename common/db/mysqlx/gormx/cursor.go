@@ -0,0 +1,308 @@
+package gormx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"gorm.io/gen/field"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultCursorPageSize = 20
+	MaxCursorPageSize     = 1000
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+
+	// cursorSecret signs the cursors, read from gormx.cursor_secret so every instance accepts the cursors of the others
+	cursorSecret = sync.OnceValue(func() []byte {
+		if secret := config.GetString("gormx.cursor_secret"); secret != "" {
+			return []byte(secret)
+		}
+
+		logger.Warn("No gormx.cursor_secret config found, cursors are signed by a random secret of this process")
+		secret := make([]byte, 32)
+		_, _ = rand.Read(secret)
+		return secret
+	})
+
+	schemaCache = &sync.Map{}
+)
+
+// CursorPagination pages by the sort field and the primary key of the last row instead of an offset,
+// the cursor is opaque to clients and signed so it cannot be forged
+type CursorPagination struct {
+	Cursor   string `form:"cursor"`
+	PageSize *int   `form:"pageSize,default=20" binding:"omitempty,min=1,max=1000"`
+}
+
+// Keyset is the query of a page of cursor pagination
+//
+//	ks, err := req.CursorPagination.ToKeyset(u, &req.Sorter, gormx.NewDescendSorter("created_at"), "id")
+//	do := u.WithContext(ctx).Order(ks.Orders...).Limit(ks.Limit)
+//	if ks.Expr != nil {
+//		do = do.Where(ks.Expr)
+//	}
+//	rows, err := do.Find()
+//	return gormx.NextPage(ks, rows)
+type Keyset struct {
+	// Expr filters the rows after the cursor, nil on the first page
+	Expr field.Expr
+	// Orders sorts by the sort field and then the primary key
+	Orders []field.Expr
+	// Limit fetches one more row than the page size to know whether there are more rows
+	Limit int
+
+	names    []string
+	desc     bool
+	pageSize int
+}
+
+type cursorPayload struct {
+	Names  []string          `json:"n"`
+	Desc   bool              `json:"d"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// ToKeyset resolves the sort field like Sorter.ToOrderExpr and decodes the cursor into the condition of the page,
+// the primary key breaks ties between rows with the same sort value, so the sort field should not be nullable
+func (p *CursorPagination) ToKeyset(dao Dao, sorter *Sorter, defaultSorter *Sorter, primaryKey string) (*Keyset, error) {
+	pageSize := DefaultCursorPageSize
+	if p.PageSize != nil {
+		pageSize = *p.PageSize
+	}
+	if pageSize < 1 || pageSize > MaxCursorPageSize {
+		return nil, core.NewParamErr(fmt.Errorf("page size must be between 1 and %d", MaxCursorPageSize))
+	}
+
+	pk, ok := dao.GetFieldByName(primaryKey)
+	if !ok {
+		return nil, core.NewParamErr(fmt.Errorf("field %s not found in table %s", primaryKey, dao.TableName()))
+	}
+
+	ks := &Keyset{
+		names:    []string{string(pk.ColumnName())},
+		desc:     true,
+		pageSize: pageSize,
+	}
+	fields := []field.OrderExpr{pk}
+	if sorter == nil {
+		sorter = &Sorter{}
+	}
+	if sorter.Field != "" || defaultSorter != nil {
+		f, desc, err := sorter.resolve(dao, defaultSorter)
+		if err != nil {
+			return nil, err
+		}
+		ks.desc = desc
+		if name := string(f.ColumnName()); name != ks.names[0] {
+			ks.names = []string{name, ks.names[0]}
+			fields = []field.OrderExpr{f, pk}
+		}
+	} else {
+		ks.desc = sorter.Order != "ascend"
+		sorter.Order = ""
+	}
+
+	for _, f := range fields {
+		if ks.desc {
+			ks.Orders = append(ks.Orders, f.Desc())
+		} else {
+			ks.Orders = append(ks.Orders, f.Asc())
+		}
+	}
+	ks.Limit = ks.pageSize + 1
+
+	cursor := p.Cursor
+	p.Cursor = ""
+	p.PageSize = nil
+	if cursor == "" {
+		return ks, nil
+	}
+
+	values, err := decodeCursor(cursor, ks.names, ks.desc)
+	if err != nil {
+		return nil, core.NewParamErr(err)
+	}
+	ks.Expr = after(fields, values, ks.desc)
+	return ks, nil
+}
+
+// after builds (a < x) OR (a = x AND b < y) for descending order and the same with > for ascending order
+func after(fields []field.OrderExpr, values []any, desc bool) field.Expr {
+	op := " > "
+	if desc {
+		op = " < "
+	}
+
+	var branches []field.Expr
+	for i, f := range fields {
+		conds := make([]field.Expr, 0, i+1)
+		for j := range i {
+			conds = append(conds, field.NewUnsafeFieldRaw("? = ?", fields[j], values[j]))
+		}
+		conds = append(conds, field.NewUnsafeFieldRaw("?"+op+"?", f, values[i]))
+		branches = append(branches, field.And(conds...))
+	}
+	return field.Or(branches...)
+}
+
+// NextPage trims the extra row fetched by Keyset.Limit and encodes the cursor of the following page from the last row
+func NextPage[T any](ks *Keyset, rows []T) (core.CursorData[[]T], error) {
+	if len(rows) <= ks.pageSize {
+		return core.CursorPaginated(rows, "", false), nil
+	}
+
+	rows = rows[:ks.pageSize]
+	values, err := rowValues(rows[len(rows)-1], ks.names)
+	if err != nil {
+		return core.CursorData[[]T]{}, err
+	}
+	cursor, err := encodeCursor(ks.names, ks.desc, values)
+	if err != nil {
+		return core.CursorData[[]T]{}, err
+	}
+	return core.CursorPaginated(rows, cursor, true), nil
+}
+
+// rowValues reads the columns of a model by its gorm schema
+func rowValues(row any, names []string) ([]any, error) {
+	s, err := schema.Parse(row, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	values := make([]any, 0, len(names))
+	for _, name := range names {
+		f := s.LookUpField(name)
+		if f == nil {
+			return nil, fmt.Errorf("field %s not found in model %s", name, s.Name)
+		}
+		value, _ := f.ValueOf(context.Background(), rv)
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func encodeCursor(names []string, desc bool, values []any) (string, error) {
+	payload := cursorPayload{Names: names, Desc: desc}
+	for _, value := range values {
+		raw, err := encodeValue(value)
+		if err != nil {
+			return "", err
+		}
+		payload.Values = append(payload.Values, raw)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(sign(data)), nil
+}
+
+func decodeCursor(cursor string, names []string, desc bool) ([]any, error) {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(data)) {
+		return nil, errInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return nil, errInvalidCursor
+	}
+	// a cursor of another sort order would skip or repeat rows
+	if !slices.Equal(payload.Names, names) || payload.Desc != desc || len(payload.Values) != len(names) {
+		return nil, fmt.Errorf("%w: sort order changed", errInvalidCursor)
+	}
+
+	values := make([]any, 0, len(payload.Values))
+	for _, raw := range payload.Values {
+		value, err := decodeValue(raw)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, cursorSecret())
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// cursorTime keeps times apart from strings, so they are compared as times with their location and fraction
+type cursorTime struct {
+	Time *time.Time `json:"t"`
+}
+
+func encodeValue(value any) (json.RawMessage, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return json.Marshal(cursorTime{Time: &v})
+	case *time.Time:
+		return json.Marshal(cursorTime{Time: v})
+	}
+	return json.Marshal(value)
+}
+
+func decodeValue(raw json.RawMessage) (any, error) {
+	if bytes.HasPrefix(raw, []byte("{")) {
+		var t cursorTime
+		if err := json.Unmarshal(raw, &t); err != nil || t.Time == nil {
+			return nil, errInvalidCursor
+		}
+		return *t.Time, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return value, nil
+}
@@ -0,0 +1,30 @@
+package gormx
+
+import "gorm.io/gorm"
+
+// Dialect of the database, named after the gorm dialector
+type Dialect string
+
+const (
+	DialectMySQL     Dialect = "mysql"
+	DialectPostgres  Dialect = "postgres"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// DialectOf returns the dialect of the client
+func DialectOf(db *gorm.DB) Dialect {
+	return Dialect(db.Dialector.Name())
+}
+
+// excluded refers to the value proposed for insertion of the column in an upsert
+func (d Dialect) excluded(column string) string {
+	switch d {
+	case DialectPostgres:
+		return "EXCLUDED." + column
+	case DialectSQLite, DialectSQLServer:
+		return "excluded." + column
+	default:
+		return "VALUES(" + column + ")"
+	}
+}
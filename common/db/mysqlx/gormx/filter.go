@@ -0,0 +1,196 @@
+package gormx
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfa-inc/gfa/core"
+	"gorm.io/gen/field"
+)
+
+// Filter operators
+const (
+	OpEq      = "eq"
+	OpNe      = "ne"
+	OpLt      = "lt"
+	OpLte     = "lte"
+	OpGt      = "gt"
+	OpGte     = "gte"
+	OpIn      = "in"
+	OpLike    = "like"
+	OpBetween = "between"
+	OpIsNull  = "isnull"
+)
+
+var (
+	filterKeyRegex = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+	comparisons = map[string]string{
+		OpEq:  "=",
+		OpNe:  "<>",
+		OpLt:  "<",
+		OpLte: "<=",
+		OpGt:  ">",
+		OpGte: ">=",
+	}
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// Filter is a condition of a list query, e.g. filter[status][in]=a,b
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// ParseFilters parses the filter[field][op]=value parameters of a query, the operator defaults to eq
+func ParseFilters(query url.Values) ([]Filter, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var filters []Filter
+	for _, key := range keys {
+		matches := filterKeyRegex.FindStringSubmatch(key)
+		if matches == nil {
+			continue
+		}
+
+		op := matches[2]
+		if op == "" {
+			op = OpEq
+		}
+		if _, ok := comparisons[op]; !ok && op != OpIn && op != OpLike && op != OpBetween && op != OpIsNull {
+			return nil, core.NewParamErr(fmt.Errorf("unsupported operator %s of filter %s", op, matches[1]))
+		}
+		for _, value := range query[key] {
+			filters = append(filters, Filter{Field: matches[1], Op: op, Value: value})
+		}
+	}
+	return filters, nil
+}
+
+// ToFilterExpr converts the filter parameters of a query to a condition of the table,
+// returning nil if there is no filter
+func ToFilterExpr(dao Dao, query url.Values) (field.Expr, error) {
+	filters, err := ParseFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	exprs := make([]field.Expr, 0, len(filters))
+	for _, filter := range filters {
+		expr, err := filter.ToExpr(dao)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return field.And(exprs...), nil
+}
+
+// ToExpr resolves the field of the filter and converts its value to the type of the field
+func (f Filter) ToExpr(dao Dao) (field.Expr, error) {
+	col, ok := dao.GetFieldByName(f.Field)
+	if !ok {
+		return nil, core.NewParamErr(fmt.Errorf("field %s not found in table %s", f.Field, dao.TableName()))
+	}
+
+	if op, ok := comparisons[f.Op]; ok {
+		value, err := f.convert(col, f.Value)
+		if err != nil {
+			return nil, err
+		}
+		return field.NewUnsafeFieldRaw("? "+op+" ?", col, value), nil
+	}
+
+	switch f.Op {
+	case OpIn:
+		values, err := f.convertAll(col, strings.Split(f.Value, ","))
+		if err != nil {
+			return nil, err
+		}
+		return field.NewUnsafeFieldRaw("? IN ?", col, values), nil
+	case OpBetween:
+		bounds := strings.Split(f.Value, ",")
+		if len(bounds) != 2 {
+			return nil, core.NewParamErr(fmt.Errorf("filter %s between requires two values", f.Field))
+		}
+		values, err := f.convertAll(col, bounds)
+		if err != nil {
+			return nil, err
+		}
+		return field.NewUnsafeFieldRaw("? BETWEEN ? AND ?", col, values[0], values[1]), nil
+	case OpLike:
+		return field.NewUnsafeFieldRaw("? LIKE ?", col, "%"+likeEscaper.Replace(f.Value)+"%"), nil
+	case OpIsNull:
+		isNull, err := strconv.ParseBool(f.Value)
+		if err != nil {
+			return nil, core.NewParamErr(fmt.Errorf("invalid value %s of filter %s", f.Value, f.Field))
+		}
+		if isNull {
+			return field.NewUnsafeFieldRaw("? IS NULL", col), nil
+		}
+		return field.NewUnsafeFieldRaw("? IS NOT NULL", col), nil
+	default:
+		return nil, core.NewParamErr(fmt.Errorf("unsupported operator %s of filter %s", f.Op, f.Field))
+	}
+}
+
+func (f Filter) convertAll(col field.OrderExpr, values []string) ([]any, error) {
+	result := make([]any, 0, len(values))
+	for _, value := range values {
+		v, err := f.convert(col, value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// convert parses the value by the gen type of the field, values of untyped fields are kept as strings
+func (f Filter) convert(col field.OrderExpr, value string) (any, error) {
+	var (
+		v   any
+		err error
+	)
+	switch col.(type) {
+	case field.Int, field.Int8, field.Int16, field.Int32, field.Int64:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case field.Uint, field.Uint8, field.Uint16, field.Uint32, field.Uint64:
+		v, err = strconv.ParseUint(value, 10, 64)
+	case field.Float32, field.Float64:
+		v, err = strconv.ParseFloat(value, 64)
+	case field.Bool:
+		v, err = strconv.ParseBool(value)
+	case field.Time:
+		v, err = parseTime(value)
+	default:
+		v = value
+	}
+	if err != nil {
+		return nil, core.NewParamErr(fmt.Errorf("invalid value %s of filter %s", value, f.Field))
+	}
+	return v, nil
+}
+
+func parseTime(value string) (t time.Time, err error) {
+	for _, layout := range []string{time.DateTime, time.RFC3339, time.DateOnly} {
+		if t, err = time.Parse(layout, value); err == nil {
+			return
+		}
+	}
+	return
+}
@@ -37,6 +37,19 @@ type Sorter struct {
 }
 
 func (s *Sorter) ToOrderExpr(dao Dao, defaultSorter *Sorter) (field.Expr, error) {
+	f, desc, err := s.resolve(dao, defaultSorter)
+	if err != nil {
+		return nil, err
+	}
+
+	if desc {
+		return f.Desc(), nil
+	}
+	return f.Asc(), nil
+}
+
+// resolve looks up the sort field, the order is descending unless it is ascend
+func (s *Sorter) resolve(dao Dao, defaultSorter *Sorter) (field.OrderExpr, bool, error) {
 	if s.Field == "" && defaultSorter != nil {
 		s.Field = defaultSorter.Field
 		s.Order = defaultSorter.Order
@@ -45,20 +58,14 @@ func (s *Sorter) ToOrderExpr(dao Dao, defaultSorter *Sorter) (field.Expr, error)
 	f, ok := dao.GetFieldByName(s.Field)
 	if !ok {
 		err := core.NewParamErr(fmt.Errorf("field %s not found in table %s", s.Field, dao.TableName()))
-		return nil, err
+		return nil, false, err
 	}
 	s.Field = ""
 
 	order := s.Order
 	s.Order = ""
 
-	if order == "ascend" {
-		return f.Asc(), nil
-	} else if order == "descend" {
-		return f.Desc(), nil
-	} else {
-		return f.Desc(), nil
-	}
+	return f, order != "ascend", nil
 }
 
 func NewDescendSorter(field string) *Sorter {
@@ -77,20 +84,27 @@ func NewAscendSorter(field string) *Sorter {
 
 type DateTimeRange struct {
 	StartTime string `form:"start_time" binding:"omitempty,datetime=2006-01-02 15:04:05"`
-	EndTime   string `form:"end_time" binding:"omitempty,datetime=2006-01-02 15:04:05"`
+	EndTime   string `form:"end_time" binding:"omitempty,datetime=2006-01-02 15:04:05,datetime_gte=StartTime"`
 }
 
-func (s *DateTimeRange) ToExpr(field field.Time) field.Expr {
+// ToExpr converts the range to a between expression, returning nil if either bound is missing
+func (s *DateTimeRange) ToExpr(field field.Time) (field.Expr, error) {
 	if s.StartTime == "" || s.EndTime == "" {
-		return nil
+		return nil, nil
 	}
 
-	startTime, _ := time.Parse(time.DateTime, s.StartTime)
-	endTime, _ := time.Parse(time.DateTime, s.EndTime)
+	startTime, err := time.Parse(time.DateTime, s.StartTime)
+	if err != nil {
+		return nil, core.NewParamErr(fmt.Errorf("invalid start_time %s", s.StartTime))
+	}
+	endTime, err := time.Parse(time.DateTime, s.EndTime)
+	if err != nil {
+		return nil, core.NewParamErr(fmt.Errorf("invalid end_time %s", s.EndTime))
+	}
 
 	s.StartTime = ""
 	s.EndTime = ""
-	return field.Between(startTime, endTime)
+	return field.Between(startTime, endTime), nil
 }
 
 // ColumnType defines the data type of a column
@@ -200,7 +214,14 @@ func BooleanColumn(name string) TypedColumn {
 //	    "numeric:age",               // prefix notation
 //	    NumericColumn("balance"),    // typed column
 //	)
+//
+// The SQL is MySQL specific, use Dialect.AssignmentNotEmptyColumns for other databases
 func AssignmentNotEmptyColumns(columns ...any) clause.Set {
+	return DialectMySQL.AssignmentNotEmptyColumns(columns...)
+}
+
+// AssignmentNotEmptyColumns is the dialect specific version of AssignmentNotEmptyColumns
+func (d Dialect) AssignmentNotEmptyColumns(columns ...any) clause.Set {
 	typedColumns := make([]TypedColumn, len(columns))
 
 	for i, col := range columns {
@@ -217,7 +238,7 @@ func AssignmentNotEmptyColumns(columns ...any) clause.Set {
 		}
 	}
 
-	return buildAssignments(typedColumns)
+	return d.buildAssignments(typedColumns)
 }
 
 // parseColumnString parses column string with optional type prefix
@@ -285,63 +306,93 @@ func parseColumnString(s string) TypedColumn {
 }
 
 // buildAssignments constructs the assignment clauses
-func buildAssignments(columns []TypedColumn) clause.Set {
+func (d Dialect) buildAssignments(columns []TypedColumn) clause.Set {
 	values := make(map[string]any)
 
 	for _, col := range columns {
-		conditions := buildEmptyConditions(col)
+		conditions := d.buildEmptyConditions(col)
+		value := d.excluded(col.Name)
 
 		if len(conditions) == 0 {
-			values[col.Name] = field.NewUnsafeFieldRaw(fmt.Sprintf("VALUES(%s)", col.Name))
+			values[col.Name] = field.NewUnsafeFieldRaw(value)
 			continue
 		}
 
-		conditionsStr := strings.Join(conditions, " OR ")
-		caseSQL := fmt.Sprintf(
-			"CASE WHEN %s THEN %s ELSE VALUES(%s) END",
-			conditionsStr, col.Name, col.Name)
-
-		values[col.Name] = field.NewUnsafeFieldRaw(caseSQL)
+		values[col.Name] = notEmptyValue{
+			dialect:    d,
+			column:     col.Name,
+			conditions: strings.Join(conditions, " OR "),
+			value:      value,
+		}
 	}
 
 	return clause.Assignments(values)
 }
 
-// buildEmptyConditions builds empty value check conditions based on column type
-func buildEmptyConditions(col TypedColumn) []string {
+// notEmptyValue keeps the current value of a column when the proposed one is empty,
+// the current value is qualified by the table on Postgres, where a bare column is ambiguous with EXCLUDED
+type notEmptyValue struct {
+	dialect    Dialect
+	column     string
+	conditions string
+	value      string
+}
+
+func (v notEmptyValue) Build(builder clause.Builder) {
+	builder.WriteString("CASE WHEN " + v.conditions + " THEN ")
+	if v.dialect == DialectPostgres {
+		builder.WriteQuoted(clause.Column{Table: clause.CurrentTable, Name: v.column})
+	} else {
+		builder.WriteString(v.column)
+	}
+	builder.WriteString(" ELSE " + v.value + " END")
+}
+
+// buildEmptyConditions builds empty value check conditions based on column type,
+// Postgres is strictly typed so only the checks valid for the column type are built
+func (d Dialect) buildEmptyConditions(col TypedColumn) []string {
 	var conditions []string
+	value := d.excluded(col.Name)
 
 	// All types check NULL and empty string
-	conditions = append(conditions, fmt.Sprintf("VALUES(%s) IS NULL", col.Name))
-	conditions = append(conditions, fmt.Sprintf("TRIM(VALUES(%s)) = ''", col.Name))
+	conditions = append(conditions, fmt.Sprintf("%s IS NULL", value))
+	if d != DialectPostgres || col.Type == TypeString {
+		conditions = append(conditions, fmt.Sprintf("TRIM(%s) = ''", value))
+	}
 
 	switch col.Type {
 	case TypeNumeric:
 		if !col.AllowZero {
-			conditions = append(conditions, fmt.Sprintf("VALUES(%s) = 0", col.Name))
+			conditions = append(conditions, fmt.Sprintf("%s = 0", value))
 		}
 		if !col.AllowNegative {
-			conditions = append(conditions, fmt.Sprintf("VALUES(%s) < 0", col.Name))
+			conditions = append(conditions, fmt.Sprintf("%s < 0", value))
 		}
 
 	case TypeUUID:
 		conditions = append(conditions,
-			fmt.Sprintf("VALUES(%s) = '00000000-0000-0000-0000-000000000000'", col.Name))
+			fmt.Sprintf("%s = '00000000-0000-0000-0000-000000000000'", value))
 
 	case TypeDateTime:
-		conditions = append(conditions, fmt.Sprintf("VALUES(%s) = '0000-00-00'", col.Name))
-		conditions = append(conditions, fmt.Sprintf("VALUES(%s) = '0000-00-00 00:00:00'", col.Name))
+		if d == DialectMySQL {
+			conditions = append(conditions, fmt.Sprintf("%s = '0000-00-00'", value))
+			conditions = append(conditions, fmt.Sprintf("%s = '0000-00-00 00:00:00'", value))
+		}
 
 	case TypeJSON:
-		conditions = append(conditions, fmt.Sprintf("VALUES(%s) = '{}'", col.Name))
-		conditions = append(conditions, fmt.Sprintf("VALUES(%s) = '[]'", col.Name))
-		conditions = append(conditions, fmt.Sprintf("VALUES(%s) = 'null'", col.Name))
+		jsonValue := value
+		if d == DialectPostgres {
+			jsonValue = fmt.Sprintf("CAST(%s AS TEXT)", value)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = '{}'", jsonValue))
+		conditions = append(conditions, fmt.Sprintf("%s = '[]'", jsonValue))
+		conditions = append(conditions, fmt.Sprintf("%s = 'null'", jsonValue))
 	}
 
 	// Custom empty values for all types
 	for _, emptyVal := range col.CustomEmptyValues {
 		conditions = append(conditions,
-			fmt.Sprintf("VALUES(%s) = '%s'", col.Name, escapeSQLString(emptyVal)))
+			fmt.Sprintf("%s = '%s'", value, escapeSQLString(emptyVal)))
 	}
 
 	return conditions
@@ -1,9 +1,24 @@
 package gormx
 
 import (
+	"errors"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/validatorx"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func TestAssignmentNotEmptyColumns(t *testing.T) {
@@ -141,3 +156,225 @@ func TestAssignmentNotEmptyColumns(t *testing.T) {
 		}
 	})
 }
+
+func TestDateTimeRange(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	validatorx.Setup()
+
+	r := DateTimeRange{StartTime: "2024-01-02 00:00:00", EndTime: "2024-01-01 00:00:00"}
+	assert.NotNil(t, binding.Validator.ValidateStruct(&r))
+
+	r = DateTimeRange{StartTime: "2024-01-01 00:00:00", EndTime: "2024-01-02 00:00:00"}
+	assert.Nil(t, binding.Validator.ValidateStruct(&r))
+	expr, err := r.ToExpr(field.NewTime("orders", "created_at"))
+	assert.Nil(t, err)
+	assert.NotNil(t, expr)
+
+	r = DateTimeRange{StartTime: "2024-01-01", EndTime: "2024-01-02 00:00:00"}
+	_, err = r.ToExpr(field.NewTime("orders", "created_at"))
+	var paramErr *core.ParamErr
+	assert.True(t, errors.As(err, &paramErr))
+
+	expr, err = (&DateTimeRange{StartTime: "2024-01-01 00:00:00"}).ToExpr(field.NewTime("orders", "created_at"))
+	assert.Nil(t, err)
+	assert.Nil(t, expr)
+}
+
+type order struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+type orderDao struct {
+	fields map[string]field.OrderExpr
+}
+
+func (orderDao) TableName() string {
+	return "orders"
+}
+
+func (d orderDao) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	f, ok := d.fields[fieldName]
+	return f, ok
+}
+
+func newOrderDao() orderDao {
+	return orderDao{fields: map[string]field.OrderExpr{
+		"id":         field.NewInt64("orders", "id"),
+		"status":     field.NewString("orders", "status"),
+		"amount":     field.NewFloat64("orders", "amount"),
+		"paid":       field.NewBool("orders", "paid"),
+		"created_at": field.NewTime("orders", "created_at"),
+	}}
+}
+
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/db", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.Nil(t, err)
+	return db
+}
+
+func TestCursorPagination(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	config.Setup()
+	config.SetDefault("gormx.cursor_secret", "secret")
+
+	db := dryRunDB(t)
+	dao := newOrderDao()
+	pageSize := 2
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []order{{3, base.Add(2 * time.Hour)}, {2, base.Add(time.Hour)}, {1, base}}
+
+	p := CursorPagination{PageSize: &pageSize}
+	ks, err := p.ToKeyset(dao, &Sorter{}, NewDescendSorter("created_at"), "id")
+	assert.Nil(t, err)
+	assert.Nil(t, ks.Expr)
+	assert.Equal(t, 3, ks.Limit)
+
+	page, err := NextPage(ks, rows)
+	assert.Nil(t, err)
+	assert.True(t, page.HasMore)
+	assert.Len(t, page.Data, 2)
+	assert.NotEmpty(t, page.NextCursor)
+	next := page.NextCursor
+
+	p = CursorPagination{Cursor: next, PageSize: &pageSize}
+	ks, err = p.ToKeyset(dao, &Sorter{}, NewDescendSorter("created_at"), "id")
+	assert.Nil(t, err)
+	stmt := db.Table("orders").Where(ks.Expr).Limit(ks.Limit).Find(&[]order{}).Statement
+	assert.Equal(t, "SELECT * FROM `orders` WHERE (`orders`.`created_at` < ? OR (`orders`.`created_at` = ? AND `orders`.`id` < ?)) LIMIT ?", stmt.SQL.String())
+	assert.Equal(t, []any{base.Add(time.Hour), base.Add(time.Hour), int64(2), 3}, stmt.Vars)
+	assert.Len(t, ks.Orders, 2)
+
+	page, err = NextPage(ks, rows[2:])
+	assert.Nil(t, err)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+
+	var paramErr *core.ParamErr
+	_, signature, _ := strings.Cut(next, ".")
+	p = CursorPagination{Cursor: "e30." + signature, PageSize: &pageSize}
+	_, err = p.ToKeyset(dao, &Sorter{}, NewDescendSorter("created_at"), "id")
+	assert.True(t, errors.As(err, &paramErr))
+
+	first, err := NextPage(&Keyset{names: []string{"created_at", "id"}, desc: true, pageSize: 1}, rows)
+	assert.Nil(t, err)
+	p = CursorPagination{Cursor: first.NextCursor, PageSize: &pageSize}
+	_, err = p.ToKeyset(dao, NewAscendSorter("created_at"), nil, "id")
+	assert.True(t, errors.As(err, &paramErr))
+
+	// page sizes out of range are rejected by the binding and by ToKeyset
+	validatorx.Setup()
+	for _, size := range []int{0, -1, MaxCursorPageSize + 1} {
+		p = CursorPagination{PageSize: &size}
+		assert.NotNil(t, binding.Validator.ValidateStruct(&p))
+		_, err = p.ToKeyset(dao, &Sorter{}, nil, "id")
+		assert.True(t, errors.As(err, &paramErr))
+	}
+	p = CursorPagination{}
+	ks, err = p.ToKeyset(dao, &Sorter{}, nil, "id")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultCursorPageSize+1, ks.Limit)
+	assert.Nil(t, binding.Validator.ValidateStruct(&p))
+}
+
+func TestToFilterExpr(t *testing.T) {
+	db := dryRunDB(t)
+	dao := newOrderDao()
+
+	query, _ := url.ParseQuery("filter[status][in]=paid,shipped&filter[created_at][between]=2024-01-01,2024-01-31 23:59:59" +
+		"&filter[amount][gte]=9.5&filter[paid]=true&filter[id][isnull]=false&filter[status][like]=a_b&page=1")
+	expr, err := ToFilterExpr(dao, query)
+	assert.Nil(t, err)
+	stmt := db.Table("orders").Where(expr).Find(&[]order{}).Statement
+	assert.Equal(t, "SELECT * FROM `orders` WHERE (`orders`.`amount` >= ? AND "+
+		"(`orders`.`created_at` BETWEEN ? AND ?) AND `orders`.`id` IS NOT NULL AND `orders`.`paid` = ? AND "+
+		"`orders`.`status` IN (?,?) AND `orders`.`status` LIKE ?)", stmt.SQL.String())
+	assert.Equal(t, []any{9.5, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC),
+		true, "paid", "shipped", `%a\_b%`}, stmt.Vars)
+
+	expr, err = ToFilterExpr(dao, url.Values{"page": {"1"}})
+	assert.Nil(t, err)
+	assert.Nil(t, expr)
+
+	var paramErr *core.ParamErr
+	for _, q := range []string{
+		"filter[unknown]=1",
+		"filter[id][regex]=1",
+		"filter[id][gt]=abc",
+		"filter[created_at][between]=2024-01-01",
+		"filter[id][isnull]=maybe",
+	} {
+		query, _ = url.ParseQuery(q)
+		_, err = ToFilterExpr(dao, query)
+		assert.True(t, errors.As(err, &paramErr), q)
+	}
+}
+
+type upsertUser struct {
+	ID   int64 `gorm:"primaryKey"`
+	Name string
+	Age  int
+}
+
+func TestDialectAssignmentNotEmptyColumns(t *testing.T) {
+	db := dryRunDB(t)
+	pg, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=gfa dbname=db"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	assert.Nil(t, err)
+	render := func(set clause.Set, column string) string {
+		for _, assignment := range set {
+			if assignment.Column.Name == column {
+				stmt := &gorm.Statement{DB: db}
+				if v, ok := assignment.Value.(notEmptyValue); ok && v.dialect == DialectPostgres {
+					stmt = &gorm.Statement{DB: pg, Table: "users"}
+				}
+				assignment.Value.(clause.Expression).Build(stmt)
+				return stmt.SQL.String()
+			}
+		}
+		return ""
+	}
+
+	assert.Equal(t, DialectMySQL, DialectOf(db))
+	set := AssignmentNotEmptyColumns("name", "numeric:age")
+	assert.Equal(t, "CASE WHEN VALUES(name) IS NULL OR TRIM(VALUES(name)) = '' THEN name ELSE VALUES(name) END", render(set, "name"))
+
+	set = DialectPostgres.AssignmentNotEmptyColumns("name", "numeric:age", "json:meta")
+	assert.Equal(t, `CASE WHEN EXCLUDED.name IS NULL OR TRIM(EXCLUDED.name) = '' THEN "users"."name" ELSE EXCLUDED.name END`, render(set, "name"))
+	assert.Equal(t, `CASE WHEN EXCLUDED.age IS NULL OR EXCLUDED.age = 0 THEN "users"."age" ELSE EXCLUDED.age END`, render(set, "age"))
+	assert.Contains(t, render(set, "meta"), "CAST(EXCLUDED.meta AS TEXT) = '{}'")
+
+	set = DialectSQLite.AssignmentNotEmptyColumns("datetime:created_at")
+	assert.Equal(t, "CASE WHEN excluded.created_at IS NULL OR TRIM(excluded.created_at) = '' THEN created_at ELSE excluded.created_at END",
+		render(set, "created_at"))
+
+	// the current value is qualified by the table of the upsert on Postgres
+	stmt := pg.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: DialectPostgres.AssignmentNotEmptyColumns("name"),
+	}).Create(&upsertUser{ID: 1}).Statement
+	assert.Contains(t, stmt.SQL.String(),
+		`ON CONFLICT ("id") DO UPDATE SET "name"=CASE WHEN EXCLUDED.name IS NULL OR TRIM(EXCLUDED.name) = '' THEN "upsert_users"."name" ELSE EXCLUDED.name END`)
+
+	// the upsert keeps the current values of the empty columns
+	lite, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, lite.AutoMigrate(&upsertUser{}))
+	upsert := func(user upsertUser) {
+		assert.Nil(t, lite.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: DialectOf(lite).AssignmentNotEmptyColumns("name", "numeric:age"),
+		}).Create(&user).Error)
+	}
+	upsert(upsertUser{ID: 1, Name: "tom", Age: 18})
+	upsert(upsertUser{ID: 1, Age: 20})
+	var user upsertUser
+	assert.Nil(t, lite.First(&user, 1).Error)
+	assert.Equal(t, upsertUser{ID: 1, Name: "tom", Age: 20}, user)
+}
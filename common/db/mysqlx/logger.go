@@ -0,0 +1,136 @@
+package mysqlx
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	glog "gorm.io/gorm/logger"
+)
+
+// DefaultSlowThreshold of queries in milliseconds
+const DefaultSlowThreshold = 200
+
+func newGormLogger(config Config) *gormLogger {
+	level, err := zapcore.ParseLevel(config.Level)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	slowThreshold := DefaultSlowThreshold
+	if config.SlowThreshold != 0 {
+		slowThreshold = config.SlowThreshold
+	}
+
+	l := logger.GetGlobalLogger().Clone(level)
+	return &gormLogger{
+		Logger:               &l,
+		slowThreshold:        time.Duration(slowThreshold) * time.Millisecond,
+		ignoreRecordNotFound: config.IgnoreRecordNotFound,
+		redact:               config.Redact,
+	}
+}
+
+// gormLogger logs errors at error, slow queries at warn and the other queries at debug
+type gormLogger struct {
+	*logger.Logger
+	slowThreshold        time.Duration
+	ignoreRecordNotFound bool
+	redact               bool
+}
+
+func (g *gormLogger) LogMode(logLevel glog.LogLevel) glog.Interface {
+	var level zapcore.Level
+	switch logLevel {
+	case glog.Warn:
+		level = zapcore.WarnLevel
+	case glog.Error:
+		level = zapcore.ErrorLevel
+	case glog.Info:
+		fallthrough
+	default:
+		level = zapcore.DebugLevel
+	}
+
+	ng := *g
+	l := g.Clone(level)
+	ng.Logger = &l
+	return &ng
+}
+
+func (g *gormLogger) Info(c context.Context, format string, args ...any) {
+	g.Infof(c, format, args...)
+}
+
+func (g *gormLogger) Warn(c context.Context, format string, args ...any) {
+	g.Warnf(c, format, args...)
+}
+
+func (g *gormLogger) Error(c context.Context, format string, args ...any) {
+	g.Errorf(c, format, args...)
+}
+
+// ParamsFilter drops the parameters of the logged SQL when redaction is enabled
+func (g *gormLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if g.redact {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (g *gormLogger) Trace(c context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !g.ignoreRecordNotFound {
+			g.trace(c, zapcore.InfoLevel, "Record not found", elapsed, fc)
+		}
+	case err != nil:
+		g.trace(c, zapcore.ErrorLevel, "SQL error: "+err.Error(), elapsed, fc)
+	case g.slowThreshold > 0 && elapsed > g.slowThreshold:
+		g.trace(c, zapcore.WarnLevel, "Slow SQL over "+g.slowThreshold.String(), elapsed, fc)
+	default:
+		g.trace(c, zapcore.DebugLevel, "SQL", elapsed, fc)
+	}
+}
+
+// trace logs the query with its elapsed time, rows and the caller outside gorm, the context adds the trace ID
+func (g *gormLogger) trace(c context.Context, level zapcore.Level, msg string, elapsed time.Duration,
+	fc func() (string, int64)) {
+	if !g.Enabled(level) {
+		return
+	}
+
+	sql, rows := fc()
+	g.WithContext(c).WithOptions(zap.WithCaller(false)).Logw(level, msg,
+		"elapsed", float64(elapsed.Microseconds())/1000,
+		"rows", rows,
+		"caller", caller(),
+		"sql", sql,
+	)
+}
+
+// caller returns the first frame outside gorm and this package, i.e. the code running the query
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		internal := strings.Contains(frame.File, "gorm.io/") ||
+			strings.HasPrefix(frame.Function, "github.com/gfa-inc/gfa/common/db/mysqlx.")
+		if !internal || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
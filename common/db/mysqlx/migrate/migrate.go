@@ -0,0 +1,454 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultTable       = "schema_migrations"
+	DefaultLockTimeout = 60 // seconds
+)
+
+var (
+	ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+	ErrDirty       = errors.New("database is dirty")
+
+	fileRegex = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+	// transactionalDDL are the dialects rolling back DDL statements with their transaction,
+	// DDL statements of MySQL commit implicitly
+	transactionalDDL = map[string]bool{"postgres": true, "sqlite": true, "sqlserver": true}
+)
+
+// Migration is a version read from the files {version}_{name}.up.sql and {version}_{name}.down.sql
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status of a migration, AppliedAt is nil if it is pending, Dirty if it failed partway
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Dirty     bool
+}
+
+// schemaMigration is a row of the version table, dirty while the statements of a migration run
+// without a transaction
+type schemaMigration struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	AppliedAt time.Time
+	Dirty     bool `gorm:"not null;default:false"`
+}
+
+type Migrator struct {
+	db          *gorm.DB
+	table       string
+	lockTimeout time.Duration
+	dryRun      bool
+	migrations  []Migration
+}
+
+type Option func(*Migrator)
+
+// WithTable sets the table recording the applied versions, default schema_migrations
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithLockTimeout sets how long to wait for the migration lock held by another instance
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithDryRun logs the statements that would be executed instead of executing them
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// New reads the migrations in the root of fsys, which is an embed.FS or os.DirFS of a directory
+func New(db *gorm.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		db:          db,
+		table:       DefaultTable,
+		lockTimeout: DefaultLockTimeout * time.Second,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	migrations, err := read(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m.migrations = migrations
+	return m, nil
+}
+
+func read(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		matches := fileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := versions[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			versions[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(versions))
+	for _, migration := range versions {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+func compare(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Status returns every migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn.Session(&gorm.Session{}))
+		if err != nil {
+			return err
+		}
+		statuses = m.statuses(applied)
+		return nil
+	})
+	return statuses, err
+}
+
+// Up applies the pending migrations in ascending order and returns them
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		statuses := m.statuses(applied)
+		if err = checkDirty(statuses); err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				continue
+			}
+			if err = m.apply(conn, status.Migration, true); err != nil {
+				return err
+			}
+			done = append(done, status.Migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// DownTo reverts the applied migrations newer than the version in descending order and returns them,
+// version 0 reverts every migration
+func (m *Migrator) DownTo(ctx context.Context, version uint64) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		statuses := m.statuses(applied)
+		if err = checkDirty(statuses); err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0; i-- {
+			status := statuses[i]
+			if status.Version <= version || status.AppliedAt == nil {
+				continue
+			}
+			if status.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", status.Version, status.Name)
+			}
+			if err = m.apply(conn, status.Migration, false); err != nil {
+				return err
+			}
+			done = append(done, status.Migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Force clears the dirty flag of a migration once its database is fixed by hand, recording it as applied,
+// or as pending if applied is false
+func (m *Migrator) Force(ctx context.Context, version uint64, applied bool) error {
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		var result *gorm.DB
+		if applied {
+			result = conn.Table(m.table).Where("version = ?", version).Update("dirty", false)
+		} else {
+			result = conn.Table(m.table).Where("version = ?", version).Delete(&schemaMigration{})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("migration %d not recorded", version)
+		}
+		logger.Infof("Migration %d has been forced %s", version, lo.Ternary(applied, "applied", "pending"))
+		return nil
+	})
+}
+
+func (m *Migrator) statuses(applied map[uint64]schemaMigration) []Status {
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			status.Dirty = row.Dirty
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkDirty refuses to migrate while a migration is dirty, its statements may have run partly
+func checkDirty(statuses []Status) error {
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("%w: migration %d_%s failed partway, fix the database and call Force",
+				ErrDirty, status.Version, status.Name)
+		}
+	}
+	return nil
+}
+
+// applied reads the applied versions, the version table is created or updated unless in dry run
+func (m *Migrator) applied(conn *gorm.DB) (map[uint64]schemaMigration, error) {
+	applied := make(map[uint64]schemaMigration)
+	if !m.dryRun {
+		if err := conn.Table(m.table).AutoMigrate(&schemaMigration{}); err != nil {
+			return nil, err
+		}
+	} else if !conn.Migrator().HasTable(m.table) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := conn.Table(m.table).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// apply runs the up or down statements of a migration and records it, in one transaction on the databases
+// with transactional DDL, otherwise the migration is dirty until its statements succeed
+func (m *Migrator) apply(conn *gorm.DB, migration Migration, up bool) error {
+	content := lo.Ternary(up, migration.Up, migration.Down)
+	if m.dryRun {
+		return m.exec(conn, migration, content)
+	}
+
+	if transactionalDDL[conn.Dialector.Name()] {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := m.exec(tx, migration, content); err != nil {
+				return err
+			}
+			return m.record(tx, migration, up)
+		})
+	}
+
+	if err := m.markDirty(conn, migration, up); err != nil {
+		return err
+	}
+	if err := m.exec(conn, migration, content); err != nil {
+		logger.Errorf("Migration %d_%s failed partway and is left dirty", migration.Version, migration.Name)
+		return err
+	}
+	return m.record(conn, migration, up)
+}
+
+func (m *Migrator) markDirty(conn *gorm.DB, migration Migration, up bool) error {
+	if up {
+		return conn.Table(m.table).Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now(),
+			Dirty:     true,
+		}).Error
+	}
+	return conn.Table(m.table).Where("version = ?", migration.Version).Update("dirty", true).Error
+}
+
+func (m *Migrator) exec(conn *gorm.DB, migration Migration, content string) error {
+	for _, statement := range split(content) {
+		if m.dryRun {
+			logger.Infof("[dry run] migration %d_%s: %s", migration.Version, migration.Name, statement)
+			continue
+		}
+		if err := conn.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// record saves an applied migration clean, or deletes a reverted one
+func (m *Migrator) record(conn *gorm.DB, migration Migration, up bool) error {
+	var err error
+	if up {
+		err = conn.Table(m.table).Save(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now(),
+		}).Error
+		logger.Infof("Migration %d_%s has been applied", migration.Version, migration.Name)
+	} else {
+		err = conn.Table(m.table).Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+		logger.Infof("Migration %d_%s has been reverted", migration.Version, migration.Name)
+	}
+	return err
+}
+
+// locked runs fn on one connection holding the named lock of MySQL, so concurrent instances migrate one by one,
+// other databases are not locked
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// the connection is shared by the statements, so each of them starts from a new session
+		conn = conn.Session(&gorm.Session{})
+		if conn.Dialector.Name() != "mysql" {
+			return fn(conn)
+		}
+
+		name := "gfa_migrate:" + m.table
+		var acquired *int
+		err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(m.lockTimeout.Seconds())).Scan(&acquired).Error
+		if err != nil {
+			return err
+		}
+		if acquired == nil || *acquired != 1 {
+			return ErrLockTimeout
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", name)
+
+		return fn(conn)
+	})
+}
+
+// split splits the content into statements by the semicolons outside quotes and comments
+func split(content string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      byte
+	)
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(content) {
+				i++
+				current.WriteByte(content[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteByte(c)
+		case c == '#' || (c == '-' && strings.HasPrefix(content[i:], "-- ")):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				i = len(content)
+				break
+			}
+			i += end
+			current.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+				break
+			}
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
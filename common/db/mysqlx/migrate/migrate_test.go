@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/db/mysqlx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var files = fstest.MapFS{
+	"0001_create_users.up.sql": {Data: []byte(`
+-- users; with a comment
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+INSERT INTO users (name) VALUES ('semi;colon');
+`)},
+	"0001_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+	"0002_add_email.up.sql":       {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+	"0002_add_email.down.sql":     {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	"0003_create_orders.up.sql":   {Data: []byte("/* orders */ CREATE TABLE orders (id INTEGER PRIMARY KEY);")},
+	"0003_create_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+	"README.md":                   {Data: []byte("ignored")},
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{})
+	assert.Nil(t, err)
+	return db
+}
+
+func versions(migrations []Migration) []uint64 {
+	result := make([]uint64, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func TestMigrator(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	dryRun, err := New(db, files, WithDryRun())
+	assert.Nil(t, err)
+	done, err := dryRun.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, versions(done))
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable(DefaultTable))
+
+	m, err := New(db, files)
+	assert.Nil(t, err)
+	done, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, versions(done))
+	assert.True(t, db.Migrator().HasColumn("users", "email"))
+	var name string
+	db.Raw("SELECT name FROM users").Scan(&name)
+	assert.Equal(t, "semi;colon", name)
+
+	done, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, done)
+
+	done, err = m.DownTo(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{3, 2}, versions(done))
+	assert.False(t, db.Migrator().HasTable("orders"))
+
+	statuses, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, statuses, 3)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.Equal(t, "add_email", statuses[1].Name)
+
+	_, err = New(db, fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1")}})
+	assert.NotNil(t, err)
+	_, err = New(db, fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1")},
+		"0001_b.up.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	broken := fstest.MapFS{
+		"0001_create_users.up.sql": files["0001_create_users.up.sql"],
+		"0002_broken.up.sql":       {Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);")},
+	}
+
+	// sqlite rolls back the DDL of a failed migration
+	db := openDB(t)
+	m, err := New(db, broken)
+	assert.Nil(t, err)
+	done, err := m.Up(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, []uint64{1}, versions(done))
+	assert.False(t, db.Migrator().HasTable("orders"))
+	statuses, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.False(t, statuses[1].Dirty)
+
+	// without transactional DDL the failed migration is left dirty
+	delete(transactionalDDL, "sqlite")
+	defer func() {
+		transactionalDDL["sqlite"] = true
+	}()
+	db = openDB(t)
+	m, err = New(db, broken)
+	assert.Nil(t, err)
+	_, err = m.Up(ctx)
+	assert.NotNil(t, err)
+	assert.True(t, db.Migrator().HasTable("orders"))
+	statuses, err = m.Status(ctx)
+	assert.Nil(t, err)
+	assert.True(t, statuses[1].Dirty)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrDirty)
+	_, err = m.DownTo(ctx, 0)
+	assert.ErrorIs(t, err, ErrDirty)
+
+	assert.Nil(t, db.Migrator().DropTable("orders"))
+	assert.Nil(t, m.Force(ctx, 2, false))
+	assert.NotNil(t, m.Force(ctx, 2, false))
+	broken["0002_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE orders (id INTEGER PRIMARY KEY);")}
+	m, err = New(db, broken)
+	assert.Nil(t, err)
+	done, err = m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{2}, versions(done))
+	statuses, err = m.Status(ctx)
+	assert.Nil(t, err)
+	assert.False(t, statuses[1].Dirty)
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{
+		"INSERT INTO t VALUES ('a;b', \"c\\\";d\")",
+		"UPDATE `x;y` SET a = 1",
+	}, split("INSERT INTO t VALUES ('a;b', \"c\\\";d\");\n# comment;\nUPDATE `x;y` SET a = 1; /* trailing; */\n-- end"))
+}
+
+func TestSetup(t *testing.T) {
+	db := openDB(t)
+	config.Setup()
+	mysqlx.Setup()
+	mysqlx.PutClient("default", db)
+	config.SetDefault("mysql.default.migrate", true)
+	Register("default", files)
+
+	assert.Nil(t, Setup(context.Background()))
+	assert.True(t, db.Migrator().HasTable("orders"))
+}
@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/db/mysqlx"
+	"github.com/gfa-inc/gfa/common/logger"
+)
+
+var sources = make(map[string]fs.FS)
+
+// Config of mysql.<name>.migrate, `migrate: true` migrates with the registered files and the defaults
+type Config struct {
+	// Dir of the migration files, used when no files are registered for the datasource
+	Dir         string
+	Table       string
+	LockTimeout int
+	DryRun      bool
+}
+
+// Register sets the migration files of the datasource, e.g. an embed.FS
+func Register(name string, fsys fs.FS) {
+	sources[name] = fsys
+}
+
+// Setup applies the pending migrations of every mysql datasource with migrate set
+func Setup(ctx context.Context) error {
+	if config.Get("mysql") == nil {
+		return nil
+	}
+
+	datasources := make(map[string]map[string]any)
+	err := config.UnmarshalKey("mysql", &datasources)
+	if err != nil {
+		return err
+	}
+
+	for name, datasource := range datasources {
+		raw, ok := datasource["migrate"]
+		if !ok || raw == false {
+			continue
+		}
+
+		option := Config{
+			Table:       DefaultTable,
+			LockTimeout: DefaultLockTimeout,
+		}
+		if raw != true {
+			if err = config.UnmarshalKey(fmt.Sprintf("mysql.%s.migrate", name), &option); err != nil {
+				return err
+			}
+		}
+
+		if err = run(ctx, name, option); err != nil {
+			return fmt.Errorf("mysql [%s] migration: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func run(ctx context.Context, name string, option Config) error {
+	fsys, ok := sources[name]
+	if !ok {
+		if option.Dir == "" {
+			return fmt.Errorf("no migration files registered or dir configured")
+		}
+		fsys = os.DirFS(option.Dir)
+	}
+
+	opts := []Option{WithTable(option.Table), WithLockTimeout(time.Duration(option.LockTimeout) * time.Second)}
+	if option.DryRun {
+		opts = append(opts, WithDryRun())
+	}
+	m, err := New(mysqlx.GetClient(name), fsys, opts...)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if option.DryRun {
+		logger.Infof("Mysql [%s] has %d pending migrations", name, len(migrations))
+		return nil
+	}
+	logger.Infof("Mysql [%s] has been migrated, %d migrations applied", name, len(migrations))
+	return nil
+}
@@ -3,16 +3,17 @@ package mysqlx
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/metrics"
 	"github.com/samber/lo"
-	"go.uber.org/zap/zapcore"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	glog "gorm.io/gorm/logger"
 )
 
 var (
@@ -28,11 +29,48 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	Default         bool
+	// Sources are the DSNs of the primaries written to, DNS is used when empty
+	Sources []string
+	// Replicas are the DSNs of the read replicas
+	Replicas []string
+	// Policy balances the connections between the sources or the replicas: random, round_robin or strict_round_robin
+	Policy string
+	// HealthCheckInterval of the sources and replicas in seconds, default 10
+	HealthCheckInterval int
+	// SlowThreshold in milliseconds over which queries are logged at warn, default 200, negative disables it
+	SlowThreshold int
+	// IgnoreRecordNotFound stops logging gorm.ErrRecordNotFound, which is logged at info otherwise
+	IgnoreRecordNotFound bool
+	// Redact logs the SQL with placeholders instead of the parameters
+	Redact bool
+	// Audit fills the create_time, update_time, creator and updater columns of the models having them
+	Audit bool
+	// Tenant scopes the queries of the models having the tenant_id column to the tenant in the context
+	Tenant bool
 }
 
 func NewClient(option Config) (client *gorm.DB, err error) {
 	dial := mysql.Open(option.DNS)
 	mysqlDial, _ := dial.(*mysql.Dialector)
+	client, err = Open(dial, option)
+	if err != nil {
+		return
+	}
+
+	if len(option.Sources) > 0 || len(option.Replicas) > 0 {
+		err = useResolver(client, option)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+
+	logger.Infof("Connecting to mysql [%s] %s", option.Name, mysqlDial.Config.DSNConfig.Addr)
+	return
+}
+
+// Open opens a client of the dialector with the logger, metrics and connection pool settings of the option
+func Open(dial gorm.Dialector, option Config) (client *gorm.DB, err error) {
 	client, err = gorm.Open(dial, &gorm.Config{
 		Logger:      newGormLogger(option),
 		PrepareStmt: false,
@@ -42,6 +80,30 @@ func NewClient(option Config) (client *gorm.DB, err error) {
 		return
 	}
 
+	if metrics.Enabled() {
+		err = client.Use(metrics.NewGormPlugin(option.Name))
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+
+	if option.Audit {
+		err = client.Use(NewAuditPlugin())
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+
+	if option.Tenant {
+		err = client.Use(NewTenantPlugin())
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+
 	var db *sql.DB
 	db, err = client.DB()
 	if err != nil {
@@ -49,6 +111,12 @@ func NewClient(option Config) (client *gorm.DB, err error) {
 		return
 	}
 
+	setConnPool(db, option)
+	return
+}
+
+// setConnPool applies the connection pool settings of the option
+func setConnPool(db *sql.DB, option Config) {
 	connMaxLifeTime := 30
 	if option.ConnMaxLifeTime > 0 {
 		connMaxLifeTime = option.ConnMaxLifeTime
@@ -66,9 +134,6 @@ func NewClient(option Config) (client *gorm.DB, err error) {
 		maxOpenConns = option.MaxOpenConns
 	}
 	db.SetMaxOpenConns(maxOpenConns)
-
-	logger.Infof("Connecting to mysql [%s] %s", option.Name, mysqlDial.Config.DSNConfig.Addr)
-	return
 }
 
 func Setup() {
@@ -115,58 +180,36 @@ func PutClient(name string, client *gorm.DB) {
 	clientPool[name] = client
 }
 
-func newGormLogger(config Config) *gormLogger {
-	level, err := zapcore.ParseLevel(config.Level)
-	if err != nil {
-		logger.Panic(err)
-	}
-
-	l := logger.GetGlobalLogger().Clone(level)
-	return &gormLogger{
-		Logger: l,
-	}
-}
-
-type gormLogger struct {
-	logger.Logger
-}
-
-func (g *gormLogger) LogMode(logLevel glog.LogLevel) glog.Interface {
-	var level zapcore.Level
-	switch logLevel {
-	case glog.Warn:
-		level = zapcore.WarnLevel
-	case glog.Error:
-		level = zapcore.ErrorLevel
-	case glog.Info:
-		fallthrough
-	default:
-		level = zapcore.DebugLevel
-	}
-
-	ng := gormLogger{
-		Logger: g.Clone(level),
+// Ping pings every client in the pool
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		db, err := client.DB()
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mysql [%s]: %w", name, err))
+		}
 	}
-	return &ng
-}
-
-func (g *gormLogger) Info(c context.Context, format string, args ...any) {
-	g.Infof(c, format, args)
+	return errors.Join(errs...)
 }
 
-func (g *gormLogger) Warn(c context.Context, format string, args ...any) {
-	g.Warnf(c, format, args)
-}
-
-func (g *gormLogger) Error(c context.Context, format string, args ...any) {
-	g.Errorf(c, format, args)
-}
-
-func (g *gormLogger) Trace(c context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
-	if !g.LevelEnabled("debug") {
-		return
+// Close closes every client in the pool
+func Close() error {
+	var errs []error
+	for name, client := range clientPool {
+		db, err := client.DB()
+		if err == nil {
+			err = db.Close()
+		}
+		if r, ok := resolvers.Load(client); ok {
+			err = errors.Join(err, r.(*resolver).close())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mysql [%s]: %w", name, err))
+		}
 	}
-	sqlContent, rowsAffected := fc()
-	elapsed := time.Since(begin)
-	g.Debugf(c, "[%dms] [rows:%d] %s", elapsed.Milliseconds(), rowsAffected, sqlContent)
+	logger.Infof("Mysql client pool has been closed")
+	return errors.Join(errs...)
 }
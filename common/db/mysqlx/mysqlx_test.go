@@ -1,11 +1,26 @@
 package mysqlx
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/utils/principal"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
 func TestNewMysqlClient(t *testing.T) {
@@ -26,3 +41,276 @@ func TestSetup(t *testing.T) {
 	assert.NotNil(t, Client)
 	assert.NotNil(t, GetClient("default"))
 }
+
+func TestResolver(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	client, err := gorm.Open(mysql.New(mysql.Config{DSN: "gfa:123456@tcp(127.0.0.1:1)/gfa", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.Nil(t, err)
+	err = useResolver(client, Config{
+		Name:                "default",
+		Replicas:            []string{"gfa:123456@tcp(127.0.0.1:1)/gfa?timeout=1s"},
+		HealthCheckInterval: 1,
+	})
+	assert.Nil(t, err)
+	v, ok := resolvers.Load(client)
+	assert.True(t, ok)
+	r := v.(*resolver)
+	defer func() {
+		assert.Nil(t, r.close())
+	}()
+	primary, _ := client.DB()
+	replica := gorm.ConnPool(r.pools[0])
+
+	connPool := func(db *gorm.DB) gorm.ConnPool {
+		var rows []map[string]any
+		return db.Table("users").Find(&rows).Statement.ConnPool
+	}
+	assert.Equal(t, replica, connPool(client))
+	assert.Equal(t, gorm.ConnPool(primary), connPool(Primary(client)))
+	assert.Equal(t, gorm.ConnPool(primary), connPool(client.WithContext(WithPrimary(context.Background()))))
+
+	// the replica is unreachable
+	r.check()
+	assert.False(t, r.healthy(replica))
+	assert.Equal(t, gorm.ConnPool(primary), connPool(client))
+
+	_, err = newPolicy("weighted")
+	assert.NotNil(t, err)
+}
+
+func TestHealthyPolicy(t *testing.T) {
+	pools := make([]gorm.ConnPool, 3)
+	for i := range pools {
+		db, err := sql.Open("mysql", "gfa:123456@tcp(127.0.0.1:1)/gfa")
+		assert.Nil(t, err)
+		pools[i] = db
+	}
+	r := &resolver{primary: pools[0]}
+	p := &healthyPolicy{resolver: r, base: dbresolver.RoundRobinPolicy()}
+
+	assert.Contains(t, pools[1:], p.Resolve(pools))
+	r.unhealthy.Store(pools[1], struct{}{})
+	assert.Equal(t, pools[2], p.Resolve(pools))
+	r.unhealthy.Store(pools[2], struct{}{})
+	assert.Equal(t, pools[0], p.Resolve(pools))
+	// sources without the primary keep balancing when every one is down
+	assert.Contains(t, pools[1:], p.Resolve(pools[1:]))
+}
+
+type account struct {
+	ID   int64
+	Name string
+}
+
+func TestTransactional(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	client, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tx.db")+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, client.AutoMigrate(&account{}))
+	defaultClient := Client
+	Client = client
+	defer func() {
+		Client = defaultClient
+	}()
+	ctx := context.Background()
+	names := func() []string {
+		var result []string
+		client.Model(&account{}).Order("id").Pluck("name", &result)
+		return result
+	}
+	errRollback := errors.New("rollback")
+
+	var hooks []string
+	err = Transactional(ctx, func(ctx context.Context) error {
+		assert.Nil(t, DB(ctx).Create(&account{Name: "outer"}).Error)
+		AfterCommit(ctx, func(ctx context.Context) {
+			hooks = append(hooks, "outer")
+		})
+
+		// the savepoint is rolled back alone
+		err := Transactional(ctx, func(ctx context.Context) error {
+			assert.Nil(t, DB(ctx).Create(&account{Name: "nested"}).Error)
+			AfterCommit(ctx, func(ctx context.Context) {
+				hooks = append(hooks, "nested")
+			})
+			return errRollback
+		}, WithPropagation(PropagationNested))
+		assert.Equal(t, errRollback, err)
+
+		err = Transactional(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(ctx context.Context) {
+				hooks = append(hooks, "joined")
+			})
+			return DB(ctx).Create(&account{Name: "joined"}).Error
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, hooks)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"outer", "joined"}, names())
+	assert.Equal(t, []string{"outer", "joined"}, hooks)
+
+	hooks = nil
+	err = Transactional(ctx, func(ctx context.Context) error {
+		err := Transactional(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(ctx context.Context) {
+				hooks = append(hooks, "new")
+			})
+			return DB(ctx).Create(&account{Name: "new"}).Error
+		}, WithPropagation(PropagationRequiresNew))
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"new"}, hooks)
+
+		AfterCommit(ctx, func(ctx context.Context) {
+			hooks = append(hooks, "rolled back")
+		})
+		assert.Nil(t, DB(ctx).Create(&account{Name: "rolled back"}).Error)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	assert.Equal(t, []string{"outer", "joined", "new"}, names())
+	assert.Equal(t, []string{"new"}, hooks)
+
+	AfterCommit(ctx, func(ctx context.Context) {
+		hooks = append(hooks, "immediate")
+	})
+	assert.Equal(t, []string{"new", "immediate"}, hooks)
+}
+
+func TestGormLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger.RegisterCore("observer", func(option logger.Config) zapcore.Core {
+		return core
+	})
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "debug"
+	})
+	config.Setup()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "logger.db")
+
+	client, err := Open(sqlite.Open(path), Config{Level: "debug", Redact: true})
+	assert.Nil(t, err)
+	assert.Nil(t, client.AutoMigrate(&account{}))
+	logs.TakeAll()
+
+	client.Logger.Warn(ctx, "%d replicas of %s", 2, "default")
+	assert.Equal(t, "2 replicas of default", logs.TakeAll()[0].Message)
+
+	assert.Nil(t, client.Exec("UPDATE accounts SET name = ? WHERE id = ?", "secret", 1).Error)
+	entry := logs.TakeAll()[0]
+	assert.Equal(t, zapcore.DebugLevel, entry.Level)
+	assert.Equal(t, "UPDATE accounts SET name = ? WHERE id = ?", entry.ContextMap()["sql"])
+	assert.Contains(t, entry.ContextMap()["caller"], "mysqlx_test.go")
+
+	assert.NotNil(t, client.Exec("SELECT * FROM missing").Error)
+	entry = logs.TakeAll()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Contains(t, entry.Message, "SQL error")
+
+	assert.Equal(t, gorm.ErrRecordNotFound, client.First(&account{}).Error)
+	entry = logs.TakeAll()[0]
+	assert.Equal(t, zapcore.InfoLevel, entry.Level)
+	assert.Equal(t, "Record not found", entry.Message)
+
+	client.Logger.Trace(ctx, time.Now().Add(-time.Second), func() (string, int64) {
+		return "SELECT SLEEP(1)", 1
+	}, nil)
+	entry = logs.TakeAll()[0]
+	assert.Equal(t, zapcore.WarnLevel, entry.Level)
+	assert.Equal(t, "Slow SQL over 200ms", entry.Message)
+	assert.Equal(t, int64(1), entry.ContextMap()["rows"])
+
+	// release mode logs the slow queries only
+	client, err = Open(sqlite.Open(path), Config{Level: "info", IgnoreRecordNotFound: true})
+	assert.Nil(t, err)
+	logs.TakeAll()
+	assert.Equal(t, gorm.ErrRecordNotFound, client.First(&account{}).Error)
+	assert.Nil(t, client.Exec("UPDATE accounts SET name = ? WHERE id = ?", "secret", 1).Error)
+	assert.Zero(t, logs.Len())
+}
+
+type document struct {
+	ID         int64
+	TenantID   string
+	Title      string
+	Creator    string
+	Updater    string
+	CreateTime *time.Time
+	UpdateTime *time.Time
+	DeleteTime gorm.DeletedAt
+}
+
+func TestAuditAndTenant(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	config.Setup()
+	client, err := Open(sqlite.Open(filepath.Join(t.TempDir(), "tenant.db")), Config{Level: "info", Audit: true, Tenant: true})
+	assert.Nil(t, err)
+	assert.Nil(t, client.AutoMigrate(&document{}))
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set(principal.UserIDKey, "alice")
+	c.Set(TenantContextKey, "t1")
+
+	doc := document{TenantID: "t2", Title: "a"}
+	assert.Nil(t, client.WithContext(c).Create(&doc).Error)
+	assert.Equal(t, "t1", doc.TenantID)
+	assert.Equal(t, "alice", doc.Creator)
+	assert.Equal(t, "alice", doc.Updater)
+	assert.NotNil(t, doc.CreateTime)
+	assert.NotNil(t, doc.UpdateTime)
+
+	other := WithUser(WithTenant(context.Background(), "t2"), "bob")
+	assert.Nil(t, client.WithContext(other).Create([]*document{{Title: "b"}, {Title: "c"}}).Error)
+
+	var docs []document
+	assert.Nil(t, client.WithContext(c).Find(&docs).Error)
+	assert.Len(t, docs, 1)
+
+	var count int64
+	assert.Nil(t, client.WithContext(other).Model(&document{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// conditions joined by OR are grouped before the tenant is added
+	assert.Nil(t, client.WithContext(other).Where("title = ?", "a").Or("title = ?", "b").Find(&docs).Error)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, "b", docs[0].Title)
+	stmt := client.WithContext(other).Session(&gorm.Session{DryRun: true}).
+		Where("title = ?", "a").Or("id = ?", doc.ID).Find(&[]document{}).Statement
+	assert.Contains(t, stmt.SQL.String(), "WHERE (title = ? OR id = ?) AND `documents`.`tenant_id` = ?")
+
+	// updates and deletes of another tenant affect no rows
+	result := client.WithContext(other).Model(&document{ID: doc.ID}).Update("title", "x")
+	assert.Nil(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+	result = client.WithContext(c).Model(&document{ID: doc.ID}).Update("title", "x")
+	assert.Equal(t, int64(1), result.RowsAffected)
+	assert.Nil(t, client.WithContext(other).Delete(&document{ID: doc.ID}).Error)
+
+	var updated document
+	assert.Nil(t, client.WithContext(WithUser(c, "carol")).Model(&updated).Where("id = ?", doc.ID).Update("title", "y").Error)
+	assert.Nil(t, client.WithContext(c).First(&updated, doc.ID).Error)
+	assert.Equal(t, "y", updated.Title)
+	assert.Equal(t, "alice", updated.Creator)
+	assert.Equal(t, "carol", updated.Updater)
+
+	assert.Nil(t, client.WithContext(c).Delete(&document{ID: doc.ID}).Error)
+	assert.Nil(t, client.WithContext(c).Find(&docs).Error)
+	assert.Len(t, docs, 0)
+	assert.Nil(t, client.WithContext(c).Unscoped().Find(&docs).Error)
+	assert.Len(t, docs, 1)
+
+	assert.ErrorIs(t, client.WithContext(context.Background()).Find(&docs).Error, ErrTenantRequired)
+	assert.Nil(t, client.WithContext(SkipTenant(context.Background())).Find(&docs).Error)
+	assert.Len(t, docs, 2)
+}
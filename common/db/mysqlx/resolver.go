@@ -0,0 +1,246 @@
+package mysqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Load balancing policies
+const (
+	PolicyRandom           = "random"
+	PolicyRoundRobin       = "round_robin"
+	PolicyStrictRoundRobin = "strict_round_robin"
+)
+
+// resolvers of the clients with sources or replicas
+var resolvers sync.Map
+
+type primaryKey struct{}
+
+// WithPrimary sends every query with the context to the primary, e.g. to read what was just written
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Primary sends the queries of the db to the primary, transactions begun by it are on the primary too
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+// resolver checks the health of the connections of the sources and replicas of a client
+type resolver struct {
+	name     string
+	primary  gorm.ConnPool
+	pools    []*sql.DB
+	addrs    map[gorm.ConnPool]string
+	interval time.Duration
+
+	unhealthy sync.Map
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func useResolver(client *gorm.DB, option Config) error {
+	primary, err := client.DB()
+	if err != nil {
+		return err
+	}
+
+	interval := 10
+	if option.HealthCheckInterval > 0 {
+		interval = option.HealthCheckInterval
+	}
+	r := &resolver{
+		name:     option.Name,
+		primary:  primary,
+		addrs:    make(map[gorm.ConnPool]string),
+		interval: time.Duration(interval) * time.Second,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	sources, err := r.open(option.Sources, option)
+	if err != nil {
+		return errors.Join(err, r.closePools())
+	}
+	replicas, err := r.open(option.Replicas, option)
+	if err != nil {
+		return errors.Join(err, r.closePools())
+	}
+	// the primary is a replica of last resort, so reads go on when every replica is out of rotation
+	if len(replicas) > 0 {
+		replicas = append(replicas, mysql.New(mysql.Config{Conn: primary, SkipInitializeWithVersion: true}))
+	}
+
+	policy := option.Policy
+	if policy == "" {
+		policy = PolicyRandom
+	}
+	base, err := newPolicy(policy)
+	if err != nil {
+		return errors.Join(err, r.closePools())
+	}
+
+	// the connections are opened with the config of the client, skip pinging them so a replica down at startup
+	// is taken out of rotation by the health check instead of failing the client
+	ping := client.Config.DisableAutomaticPing
+	client.Config.DisableAutomaticPing = true
+	err = client.Use(dbresolver.Register(dbresolver.Config{
+		Sources:  sources,
+		Replicas: replicas,
+		Policy:   &healthyPolicy{resolver: r, base: base},
+	}))
+	client.Config.DisableAutomaticPing = ping
+	if err != nil {
+		return errors.Join(err, r.closePools())
+	}
+
+	err = errors.Join(
+		client.Callback().Query().Before("gorm:query").Register("gfa:primary", forcePrimary),
+		client.Callback().Row().Before("gorm:row").Register("gfa:primary", forcePrimary),
+		client.Callback().Raw().Before("gorm:raw").Register("gfa:primary", forcePrimary),
+	)
+	if err != nil {
+		return errors.Join(err, r.closePools())
+	}
+
+	resolvers.Store(client, r)
+	go r.run()
+	logger.Infof("Mysql [%s] resolves %d sources and %d replicas by %s policy",
+		option.Name, len(option.Sources), len(option.Replicas), policy)
+	return nil
+}
+
+// forcePrimary resolves the connection again as a write when the context asks for the primary
+func forcePrimary(db *gorm.DB) {
+	if primary, _ := db.Statement.Context.Value(primaryKey{}).(bool); primary {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
+
+func newPolicy(name string) (dbresolver.Policy, error) {
+	switch name {
+	case PolicyRandom:
+		return dbresolver.RandomPolicy{}, nil
+	case PolicyRoundRobin:
+		return dbresolver.RoundRobinPolicy(), nil
+	case PolicyStrictRoundRobin:
+		return dbresolver.StrictRoundRobinPolicy(), nil
+	default:
+		return nil, fmt.Errorf("unsupported policy %s", name)
+	}
+}
+
+// open opens the connections of the DSNs with the pool settings of the option
+func (r *resolver) open(dsns []string, option Config) ([]gorm.Dialector, error) {
+	dialectors := make([]gorm.Dialector, 0, len(dsns))
+	for _, dsn := range dsns {
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		db, err := sql.Open(mysql.DefaultDriverName, dsn)
+		if err != nil {
+			return nil, err
+		}
+		setConnPool(db, option)
+
+		r.pools = append(r.pools, db)
+		r.addrs[db] = cfg.Addr
+		dialectors = append(dialectors, mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}))
+	}
+	return dialectors, nil
+}
+
+func (r *resolver) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// check pings every connection, taking the failing ones out of rotation until they recover
+func (r *resolver) check() {
+	for _, pool := range r.pools {
+		ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+		err := pool.PingContext(ctx)
+		cancel()
+
+		if err != nil {
+			if _, loaded := r.unhealthy.LoadOrStore(gorm.ConnPool(pool), struct{}{}); !loaded {
+				logger.Warnf("Mysql [%s] %s is out of rotation: %s", r.name, r.addrs[pool], err)
+			}
+		} else if _, loaded := r.unhealthy.LoadAndDelete(gorm.ConnPool(pool)); loaded {
+			logger.Infof("Mysql [%s] %s is back in rotation", r.name, r.addrs[pool])
+		}
+	}
+}
+
+func (r *resolver) healthy(pool gorm.ConnPool) bool {
+	_, unhealthy := r.unhealthy.Load(pool)
+	return !unhealthy
+}
+
+func (r *resolver) close() error {
+	close(r.stop)
+	<-r.done
+	return r.closePools()
+}
+
+func (r *resolver) closePools() error {
+	var errs []error
+	for _, pool := range r.pools {
+		errs = append(errs, pool.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// healthyPolicy balances between the healthy connections other than the primary,
+// falling back to the primary and then to every connection
+type healthyPolicy struct {
+	resolver *resolver
+	base     dbresolver.Policy
+}
+
+func (p *healthyPolicy) Resolve(pools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(pools))
+	hasPrimary := false
+	for _, pool := range pools {
+		if pool == p.resolver.primary {
+			hasPrimary = true
+			continue
+		}
+		if p.resolver.healthy(pool) {
+			healthy = append(healthy, pool)
+		}
+	}
+
+	switch {
+	case len(healthy) == 1:
+		return healthy[0]
+	case len(healthy) > 1:
+		return p.base.Resolve(healthy)
+	case hasPrimary:
+		return p.resolver.primary
+	default:
+		return p.base.Resolve(pools)
+	}
+}
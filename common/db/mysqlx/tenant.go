@@ -0,0 +1,119 @@
+package mysqlx
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TenantContextKey of the tenant set on the gin.Context, e.g. c.Set(mysqlx.TenantContextKey, tenantID)
+const TenantContextKey = "tenant_id"
+
+var ErrTenantRequired = errors.New("tenant required by the query of a tenant table")
+
+type (
+	tenantKey     struct{}
+	skipTenantKey struct{}
+)
+
+// WithTenant sets the tenant of the queries with the context
+func WithTenant(ctx context.Context, tenantID any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// SkipTenant disables the tenant scope of the queries with the context, e.g. for admin jobs across tenants
+func SkipTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+// Tenant returns the tenant set by WithTenant or on the gin.Context
+func Tenant(ctx context.Context) (any, bool) {
+	if tenantID := ctx.Value(tenantKey{}); tenantID != nil {
+		return tenantID, true
+	}
+	if tenantID := ctx.Value(TenantContextKey); tenantID != nil {
+		return tenantID, true
+	}
+	return nil, false
+}
+
+// TenantPlugin scopes the queries of the models having the tenant column to the tenant in the context
+// and sets it on insert and update, queries without a tenant fail with ErrTenantRequired unless SkipTenant is used
+type TenantPlugin struct {
+	Column string
+}
+
+// NewTenantPlugin creates the plugin of the tenant_id column
+func NewTenantPlugin() *TenantPlugin {
+	return &TenantPlugin{Column: "tenant_id"}
+}
+
+func (p *TenantPlugin) Name() string {
+	return "gfa:tenant"
+}
+
+func (p *TenantPlugin) Initialize(db *gorm.DB) error {
+	return errors.Join(
+		db.Callback().Create().Before("gorm:create").Register("gfa:tenant", p.set),
+		db.Callback().Update().Before("gorm:update").Register("gfa:tenant", p.scopeAndSet),
+		db.Callback().Query().Before("gorm:query").Register("gfa:tenant", p.scope),
+		db.Callback().Delete().Before("gorm:delete").Register("gfa:tenant", p.scope),
+		db.Callback().Row().Before("gorm:row").Register("gfa:tenant", p.scope),
+	)
+}
+
+// tenant returns the tenant of a statement on a tenant table, ok is false if the statement is not scoped
+func (p *TenantPlugin) tenant(db *gorm.DB) (string, any, bool) {
+	if db.Statement.Schema == nil {
+		return "", nil, false
+	}
+	field := lookUpField(db.Statement.Schema, p.Column)
+	if field == nil {
+		return "", nil, false
+	}
+	if skip, _ := db.Statement.Context.Value(skipTenantKey{}).(bool); skip {
+		return "", nil, false
+	}
+
+	tenantID, ok := Tenant(db.Statement.Context)
+	if !ok {
+		_ = db.AddError(ErrTenantRequired)
+		return "", nil, false
+	}
+	return field.DBName, tenantID, true
+}
+
+func (p *TenantPlugin) set(db *gorm.DB) {
+	if column, tenantID, ok := p.tenant(db); ok {
+		db.Statement.SetColumn(column, tenantID, true)
+	}
+}
+
+func (p *TenantPlugin) scope(db *gorm.DB) {
+	if column, tenantID, ok := p.tenant(db); ok {
+		addTenantCondition(db, column, tenantID)
+	}
+}
+
+func (p *TenantPlugin) scopeAndSet(db *gorm.DB) {
+	if column, tenantID, ok := p.tenant(db); ok {
+		db.Statement.SetColumn(column, tenantID, true)
+		addTenantCondition(db, column, tenantID)
+	}
+}
+
+// addTenantCondition ANDs the tenant onto the conditions of the statement grouped as one,
+// so conditions joined by OR can't match the rows of other tenants
+func addTenantCondition(db *gorm.DB, column string, tenantID any) {
+	eq := clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: tenantID}
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			c.Expression = clause.Where{Exprs: []clause.Expression{clause.And(where.Exprs...), eq}}
+			db.Statement.Clauses["WHERE"] = c
+			return
+		}
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{eq}})
+}
@@ -0,0 +1,134 @@
+package mysqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// Propagation decides how Transactional behaves when the context is already in a transaction
+type Propagation int
+
+const (
+	// PropagationRequired joins the current transaction or begins a new one
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew always begins a new transaction on another connection, independent of the current one
+	PropagationRequiresNew
+	// PropagationNested rolls back to a savepoint of the current transaction on error, or begins a new one
+	PropagationNested
+)
+
+type txKey struct {
+	client *gorm.DB
+}
+
+// currentTxKey is the innermost transaction of any client
+type currentTxKey struct{}
+
+// tx is a transaction in the context, hooks are run after the outermost transaction commits
+type tx struct {
+	db    *gorm.DB
+	hooks []func(ctx context.Context)
+}
+
+type txOptions struct {
+	client      *gorm.DB
+	propagation Propagation
+	sqlOptions  []*sql.TxOptions
+}
+
+type TxOption func(*txOptions)
+
+// WithPropagation sets the propagation, default PropagationRequired
+func WithPropagation(propagation Propagation) TxOption {
+	return func(o *txOptions) {
+		o.propagation = propagation
+	}
+}
+
+// WithClient runs the transaction on the named client instead of the default Client
+func WithClient(name string) TxOption {
+	return func(o *txOptions) {
+		o.client = GetClient(name)
+	}
+}
+
+// WithTxOptions sets the isolation level and read only mode of new transactions
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		o.sqlOptions = []*sql.TxOptions{opts}
+	}
+}
+
+// Transactional runs fn in a transaction stored in its context, DB(ctx) returns the transaction inside fn.
+// The transaction is rolled back when fn returns an error or panics
+func Transactional(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	option := txOptions{client: Client}
+	for _, opt := range opts {
+		opt(&option)
+	}
+
+	key := txKey{client: option.client}
+	current, inTx := ctx.Value(key).(*tx)
+	switch {
+	case inTx && option.propagation == PropagationRequired:
+		return fn(ctx)
+	case inTx && option.propagation == PropagationNested:
+		nested := &tx{}
+		// gorm begins a savepoint when a transaction is begun in a transaction
+		err := current.db.Transaction(func(db *gorm.DB) error {
+			nested.db = db
+			return fn(withTx(ctx, key, nested))
+		})
+		if err == nil {
+			current.hooks = append(current.hooks, nested.hooks...)
+		}
+		return err
+	}
+
+	t := &tx{}
+	err := option.client.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		t.db = db
+		return fn(withTx(ctx, key, t))
+	}, option.sqlOptions...)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range t.hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+func withTx(ctx context.Context, key txKey, t *tx) context.Context {
+	return context.WithValue(context.WithValue(ctx, key, t), currentTxKey{}, t)
+}
+
+// DB returns the transaction of the default Client in the context, or the Client if there is none
+func DB(ctx context.Context) *gorm.DB {
+	return dbOf(ctx, Client)
+}
+
+// DBOf returns the transaction of the named client in the context, or the client if there is none
+func DBOf(ctx context.Context, name string) *gorm.DB {
+	return dbOf(ctx, GetClient(name))
+}
+
+func dbOf(ctx context.Context, client *gorm.DB) *gorm.DB {
+	if t, ok := ctx.Value(txKey{client: client}).(*tx); ok {
+		return t.db.WithContext(ctx)
+	}
+	return client.WithContext(ctx)
+}
+
+// AfterCommit runs the hook after the innermost transaction in the context commits, e.g. to publish messages,
+// it is discarded if the transaction or its savepoint is rolled back, and run at once outside a transaction
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	if t, ok := ctx.Value(currentTxKey{}).(*tx); ok {
+		t.hooks = append(t.hooks, hook)
+		return
+	}
+	hook(ctx)
+}
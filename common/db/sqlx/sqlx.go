@@ -0,0 +1,154 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/db/mysqlx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/glebarez/sqlite"
+	"github.com/samber/lo"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+// Supported drivers
+const (
+	DriverMySQL     = "mysql"
+	DriverPostgres  = "postgres"
+	DriverSQLServer = "sqlserver"
+	DriverSQLite    = "sqlite"
+)
+
+var (
+	Client     *gorm.DB
+	clientPool map[string]*gorm.DB
+)
+
+// Config of a datasource, the driver defaults to mysql and the other keys are the same as mysqlx
+type Config struct {
+	mysqlx.Config `mapstructure:",squash"`
+	Driver        string
+}
+
+// Dialector opens the dialector of the driver
+func Dialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "", DriverMySQL:
+		return mysql.Open(dsn), nil
+	case DriverPostgres, "postgresql", "pgx":
+		return postgres.Open(dsn), nil
+	case DriverSQLServer, "mssql":
+		return sqlserver.Open(dsn), nil
+	case DriverSQLite, "sqlite3":
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported driver %s", driver)
+	}
+}
+
+// NewClient opens a client of the driver, mysql clients are opened by mysqlx with their sources and replicas,
+// which are not supported by the other drivers
+func NewClient(option Config) (client *gorm.DB, err error) {
+	dial, err := Dialector(option.Driver, option.DNS)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if dial.Name() == DriverMySQL {
+		return mysqlx.NewClient(option.Config)
+	}
+	if len(option.Sources) > 0 || len(option.Replicas) > 0 || option.Policy != "" {
+		err = fmt.Errorf("sources, replicas and policy of database [%s] are only supported by mysql", option.Name)
+		logger.Error(err)
+		return
+	}
+
+	client, err = mysqlx.Open(dial, option.Config)
+	if err != nil {
+		return
+	}
+
+	logger.Infof("Connecting to %s [%s]", dial.Name(), option.Name)
+	return
+}
+
+func Setup() {
+	clientPool = make(map[string]*gorm.DB)
+
+	if config.Get("database") == nil {
+		logger.Debug("No database config found")
+		return
+	}
+
+	configMap := make(map[string]Config)
+	err := config.UnmarshalKey("database", &configMap)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	logger.Infof("Starting to initialize database client pool")
+	for name, option := range configMap {
+		option.Name = name
+		client, err := NewClient(option)
+		if err != nil {
+			logger.Panic(err)
+		}
+		PutClient(name, client)
+
+		if option.Default {
+			Client = client
+		}
+	}
+
+	logger.Infof("Database client pool has been initialized with %d clients, clients: %s",
+		len(clientPool), strings.Join(lo.Keys(clientPool), ", "))
+}
+
+func GetClient(name string) *gorm.DB {
+	client, ok := clientPool[name]
+	if !ok {
+		logger.Panicf("Database Client %s not found", name)
+	}
+	return client
+}
+
+func PutClient(name string, client *gorm.DB) {
+	clientPool[name] = client
+}
+
+// Ping pings every client in the pool
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		db, err := client.DB()
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("database [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every client in the pool
+func Close() error {
+	var errs []error
+	for name, client := range clientPool {
+		db, err := client.DB()
+		if err == nil {
+			err = db.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("database [%s]: %w", name, err))
+		}
+	}
+	logger.Infof("Database client pool has been closed")
+	return errors.Join(errs...)
+}
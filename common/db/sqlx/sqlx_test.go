@@ -0,0 +1,69 @@
+package sqlx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/db/mysqlx/gormx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialector(t *testing.T) {
+	for driver, name := range map[string]string{
+		"":          "mysql",
+		"postgres":  "postgres",
+		"sqlserver": "sqlserver",
+		"sqlite":    "sqlite",
+	} {
+		dial, err := Dialector(driver, "")
+		assert.Nil(t, err)
+		assert.Equal(t, name, dial.Name())
+	}
+
+	_, err := Dialector("oracle", "")
+	assert.NotNil(t, err)
+}
+
+func TestSetup(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	config.Setup()
+	config.SetDefault("database.default.driver", "sqlite")
+	config.SetDefault("database.default.dns", "file::memory:")
+	config.SetDefault("database.default.default", true)
+
+	Setup()
+	defer func() {
+		assert.Nil(t, Close())
+	}()
+	assert.NotNil(t, Client)
+	assert.Equal(t, Client, GetClient("default"))
+	assert.Equal(t, gormx.DialectSQLite, gormx.DialectOf(Client))
+	assert.Nil(t, Ping(context.Background()))
+
+	var one int
+	assert.Nil(t, Client.Raw("select 1").Scan(&one).Error)
+	assert.Equal(t, 1, one)
+}
+
+func TestNewClientResolver(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	option := Config{Driver: DriverSQLite}
+	option.Name = "report"
+	option.DNS = "file::memory:"
+	option.Replicas = []string{"file::memory:"}
+	_, err := NewClient(option)
+	assert.NotNil(t, err)
+
+	option.Replicas = nil
+	client, err := NewClient(option)
+	assert.Nil(t, err)
+	db, err := client.DB()
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+}
@@ -0,0 +1,244 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/middlewares/accesslog"
+	"github.com/gfa-inc/gfa/middlewares/security"
+	"github.com/gfa-inc/gfa/utils/httpmethod"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "UP"
+	StatusDown Status = "DOWN"
+)
+
+const (
+	DefaultTimeout       = 3 // seconds
+	DefaultHealthPath    = "/healthz"
+	DefaultReadinessPath = "/readyz"
+	DefaultLivenessPath  = "/livez"
+)
+
+var (
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+// Checker reports the health of a component, a nil error means healthy
+type Checker func(ctx context.Context) error
+
+type Config struct {
+	Enable        bool
+	Timeout       int // seconds per check
+	HealthPath    string
+	ReadinessPath string
+	LivenessPath  string
+}
+
+// Result is the health of a single component
+type Result struct {
+	Status  Status `json:"status"`
+	Latency int64  `json:"latency"` // milliseconds
+	Error   string `json:"error,omitempty"`
+}
+
+// Report aggregates the results of all the registered checks
+type Report struct {
+	Status     Status            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	Components map[string]Result `json:"components,omitempty"`
+}
+
+var (
+	mu           sync.RWMutex
+	checkers     = make(map[string]Checker)
+	timeout      = DefaultTimeout * time.Second
+	shuttingDown atomic.Bool
+)
+
+// Register adds a named check, registering the same name again replaces the previous check
+func Register(name string, checker Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers[name] = checker
+}
+
+// Unregister removes a named check
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(checkers, name)
+}
+
+// MarkShuttingDown makes the readiness check fail from now on
+func MarkShuttingDown() {
+	if !shuttingDown.Swap(true) {
+		logger.Info("Readiness check marked as failing, server is shutting down")
+	}
+}
+
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Check runs every registered check concurrently, each one bounded by the configured timeout
+func Check(ctx context.Context) Report {
+	mu.RLock()
+	snapshot := make(map[string]Checker, len(checkers))
+	for k, v := range checkers {
+		snapshot[k] = v
+	}
+	mu.RUnlock()
+
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]Result, len(snapshot)),
+	}
+
+	var (
+		wg     sync.WaitGroup
+		guard  sync.Mutex
+		downed []string
+	)
+	for name, checker := range snapshot {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runCheck(ctx, checker, timeout)
+
+			guard.Lock()
+			defer guard.Unlock()
+			report.Components[name] = result
+			if result.Status == StatusDown {
+				downed = append(downed, name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(downed) > 0 {
+		sort.Strings(downed)
+		report.Status = StatusDown
+		logger.TWarnf(ctx, "Health check failed, components: %s", strings.Join(downed, ", "))
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, checker Checker, timeout time.Duration) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- errors.New("panic in health check")
+				logger.TErrorf(ctx, "panic in health check: %v", err)
+			}
+		}()
+		done <- checker(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result = Result{
+		Status:  StatusUp,
+		Latency: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness only reports that the process is able to serve requests
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusUp})
+}
+
+// Readiness fails as soon as the server starts shutting down, otherwise runs every check
+func Readiness(c *gin.Context) {
+	if IsShuttingDown() {
+		c.JSON(http.StatusServiceUnavailable, Report{
+			Status: StatusDown,
+			Error:  ErrShuttingDown.Error(),
+		})
+		return
+	}
+
+	Health(c)
+}
+
+// Health runs every check and returns the report of each component
+func Health(c *gin.Context) {
+	report := Check(c)
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+func Setup(r *gin.RouterGroup) {
+	option := Config{
+		Enable:        true,
+		Timeout:       DefaultTimeout,
+		HealthPath:    DefaultHealthPath,
+		ReadinessPath: DefaultReadinessPath,
+		LivenessPath:  DefaultLivenessPath,
+	}
+	err := config.UnmarshalKey("health", &option)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	if !option.Enable {
+		logger.Debug("Health endpoints disabled")
+		return
+	}
+
+	if option.Timeout > 0 {
+		timeout = time.Duration(option.Timeout) * time.Second
+	}
+
+	routes := map[string]gin.HandlerFunc{
+		option.HealthPath:    Health,
+		option.ReadinessPath: Readiness,
+		option.LivenessPath:  Liveness,
+	}
+	for path, handler := range routes {
+		r.GET(path, handler)
+		r.HEAD(path, handler)
+		security.PermitRoute(path, httpmethod.MethodGet|httpmethod.MethodHead)
+		accesslog.PermitRoute(path, httpmethod.MethodGet|httpmethod.MethodHead)
+	}
+
+	mu.RLock()
+	names := lo.Keys(checkers)
+	mu.RUnlock()
+	sort.Strings(names)
+	logger.Infof("Health endpoints enabled, paths: %s, checks: %s",
+		strings.Join([]string{option.HealthPath, option.ReadinessPath, option.LivenessPath}, ", "),
+		strings.Join(names, ", "))
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	config.Setup(config.WithPath("../../"))
+	logger.Setup()
+
+	timeout = 100 * time.Millisecond
+	Register("ok", func(ctx context.Context) error {
+		return nil
+	})
+	Register("failed", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	defer func() {
+		Unregister("ok")
+		Unregister("failed")
+		Unregister("slow")
+	}()
+
+	report := Check(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Components["ok"].Status)
+	assert.Equal(t, "connection refused", report.Components["failed"].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+}
+
+func TestEndpoints(t *testing.T) {
+	config.Setup(config.WithPath("../../"))
+	logger.Setup()
+
+	engine := gin.New()
+	Setup(engine.Group(config.GetString("server.base_path")))
+	Register("ok", func(ctx context.Context) error {
+		return nil
+	})
+	defer Unregister("ok")
+
+	request := func(path string) (int, Report) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, config.GetString("server.base_path")+path, nil)
+		engine.ServeHTTP(w, req)
+
+		var report Report
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	code, report := request(DefaultHealthPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusUp, report.Components["ok"].Status)
+
+	code, _ = request(DefaultReadinessPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	MarkShuttingDown()
+	defer shuttingDown.Store(false)
+
+	code, report = request(DefaultReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Error)
+
+	code, _ = request(DefaultLivenessPath)
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a consecutive-failure circuit breaker. Once open it rejects calls until
+// the timeout elapses, then lets a single probe through to decide whether to close again.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	timeout   time.Duration
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, timeout time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		timeout:   timeout,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a call may be sent, and whether it is the probe of a half-open breaker
+func (b *breaker) allow() (bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.timeout {
+			return false, false
+		}
+		b.state = breakerHalfOpen
+		return true, true
+	case breakerHalfOpen:
+		// a probe is already in flight
+		return false, false
+	default:
+		return true, false
+	}
+}
+
+// release gives back the slot of a probe cancelled by its caller, which tells nothing about the host,
+// so the next call is let through as the probe instead
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// record updates the breaker with the outcome of an allowed call
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,140 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/samber/lo"
+)
+
+var (
+	Client     *http.Client
+	clientPool map[string]*http.Client
+)
+
+// Config of a named client, all durations are in milliseconds
+type Config struct {
+	Name string
+	// Timeout of a single attempt, default 10000
+	Timeout int
+	// Hosts overrides the attempt timeout per host[:port]
+	Hosts []HostConfig
+	// MaxRetries of idempotent requests, default 2, negative disables retries
+	MaxRetries int
+	// RetryWaitMin is the first backoff, doubled on every retry, default 100
+	RetryWaitMin int
+	// RetryWaitMax caps the backoff, default 2000
+	RetryWaitMax int
+	// BreakerThreshold is the number of consecutive failures opening the circuit of a host, default 5, negative disables it
+	BreakerThreshold int
+	// BreakerTimeout is how long an open circuit rejects calls before letting a probe through, default 30000
+	BreakerTimeout int
+	// MaxIdleConnsPerHost of the underlying transport, default 10
+	MaxIdleConnsPerHost int
+	Default             bool
+}
+
+type HostConfig struct {
+	Host    string
+	Timeout int
+}
+
+func millis(v int, def int) time.Duration {
+	if v <= 0 {
+		v = def
+	}
+	return time.Duration(v) * time.Millisecond
+}
+
+func NewClient(option Config) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = 10
+	if option.MaxIdleConnsPerHost > 0 {
+		base.MaxIdleConnsPerHost = option.MaxIdleConnsPerHost
+	}
+
+	maxRetries := 2
+	if option.MaxRetries < 0 {
+		maxRetries = 0
+	} else if option.MaxRetries > 0 {
+		maxRetries = option.MaxRetries
+	}
+
+	breakerThreshold := 5
+	if option.BreakerThreshold < 0 {
+		breakerThreshold = 0
+	} else if option.BreakerThreshold > 0 {
+		breakerThreshold = option.BreakerThreshold
+	}
+
+	t := &transport{
+		name:             option.Name,
+		base:             base,
+		timeout:          millis(option.Timeout, 10000),
+		hostTimeouts:     make(map[string]time.Duration),
+		maxRetries:       maxRetries,
+		retryWaitMin:     millis(option.RetryWaitMin, 100),
+		retryWaitMax:     millis(option.RetryWaitMax, 2000),
+		breakerThreshold: breakerThreshold,
+		breakerTimeout:   millis(option.BreakerTimeout, 30000),
+		breakers:         make(map[string]*breaker),
+	}
+	for _, host := range option.Hosts {
+		t.hostTimeouts[host.Host] = millis(host.Timeout, int(t.timeout.Milliseconds()))
+	}
+
+	logger.Debugf("Http client [%s] created, timeout: %s, retries: %d", option.Name, t.timeout, maxRetries)
+	return &http.Client{Transport: t}
+}
+
+func Setup() {
+	clientPool = make(map[string]*http.Client)
+
+	if config.Get("http_client") == nil {
+		logger.Debug("No http client config found")
+		return
+	}
+
+	configMap := make(map[string]Config)
+	err := config.UnmarshalKey("http_client", &configMap)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	logger.Infof("Starting to initialize http client pool")
+	for name, option := range configMap {
+		option.Name = name
+		client := NewClient(option)
+		PutClient(name, client)
+
+		if option.Default {
+			Client = client
+		}
+	}
+
+	logger.Infof("Http client pool has been initialized with %d clients, clients: %s",
+		len(clientPool), strings.Join(lo.Keys(clientPool), ", "))
+}
+
+func GetClient(name string) *http.Client {
+	client, ok := clientPool[name]
+	if !ok {
+		logger.Panicf("Http client %s not found", name)
+	}
+	return client
+}
+
+func PutClient(name string, client *http.Client) {
+	clientPool[name] = client
+}
+
+// Close closes the idle connections of every client in the pool
+func Close() {
+	for _, client := range clientPool {
+		client.CloseIdleConnections()
+	}
+	logger.Infof("Http client pool has been closed")
+}
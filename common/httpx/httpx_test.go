@@ -0,0 +1,163 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/middlewares/requestid"
+	"github.com/gfa-inc/gfa/middlewares/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup() {
+	config.Setup(config.WithPath("../../"))
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "debug"
+	})
+}
+
+func TestRetry(t *testing.T) {
+	setup()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{Name: "test", RetryWaitMin: 1, RetryWaitMax: 5})
+
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+	_ = resp.Body.Close()
+
+	// non idempotent requests are sent once
+	calls.Store(0)
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+	_ = resp.Body.Close()
+}
+
+func TestHostTimeout(t *testing.T) {
+	setup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := NewClient(Config{
+		Name:       "test",
+		MaxRetries: -1,
+		Hosts:      []HostConfig{{Host: u.Host, Timeout: 10}},
+	})
+
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// hosts without a timeout fall back to the timeout of the client
+	client = NewClient(Config{Name: "test", MaxRetries: -1, Hosts: []HostConfig{{Host: u.Host}}})
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	setup()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{Name: "test", MaxRetries: -1, BreakerThreshold: 2, BreakerTimeout: 50})
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+	}
+
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// a probe is let through once the breaker timeout elapses
+	time.Sleep(60 * time.Millisecond)
+	resp, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(3), calls.Load())
+
+	// a probe cancelled by its caller lets the next call probe
+	time.Sleep(60 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err = client.Do(req)
+	assert.True(t, errors.Is(err, context.Canceled))
+	resp, err = client.Get(server.URL)
+	assert.Nil(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestPropagation(t *testing.T) {
+	setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+		option.Tracing = &logger.TracingConfig{
+			Enabled:  true,
+			Exporter: &logger.ExporterConfig{Type: logger.ExporterMemory},
+		}
+	})
+	defer func() {
+		_ = logger.ShutdownTracing(t.Context())
+	}()
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{Name: "test"})
+	engine := gin.New()
+	engine.Use(requestid.RequestID(), tracing.Tracing())
+	engine.GET("/", func(c *gin.Context) {
+		req, _ := http.NewRequestWithContext(c, http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		_ = resp.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "abc", header.Get("X-Request-ID"))
+	assert.True(t, strings.HasPrefix(header.Get("traceparent"), "00-0af7651916cd43dd8448eb211c80319c-"))
+	assert.Len(t, logger.MemoryExporter().GetSpans(), 2)
+}
@@ -0,0 +1,244 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/middlewares/requestid"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const defaultRequestIDHeader = "X-Request-ID"
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// transport adds per-host timeouts, retries, circuit breaking, trace propagation and logging to a base RoundTripper
+type transport struct {
+	name         string
+	base         http.RoundTripper
+	timeout      time.Duration
+	hostTimeouts map[string]time.Duration
+	maxRetries   int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+
+	breakerThreshold int
+	breakerTimeout   time.Duration
+	mu               sync.Mutex
+	breakers         map[string]*breaker
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+
+	spanCtx, span := logger.StartSpan(traceContext(ctx), "HTTP "+req.Method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		))
+	defer span.End()
+
+	maxAttempts := 1
+	if isRetryable(req) {
+		maxAttempts += t.maxRetries
+	}
+
+	var (
+		resp     *http.Response
+		err      error
+		attempts int
+	)
+	for attempts < maxAttempts {
+		if attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(t.backoff(attempts)):
+			}
+		}
+
+		resp, err = t.roundTrip(req, spanCtx, attempts)
+		attempts++
+		if attempts == maxAttempts || !shouldRetry(ctx, resp, err) {
+			break
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+	}
+
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.TWarnf(ctx, "[%s] %s %s %dms attempts=%d %s", t.name, req.Method, req.URL, latency, attempts, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	logger.TDebugf(ctx, "[%s] [%d] %s %s %dms attempts=%d", t.name, resp.StatusCode, req.Method, req.URL, latency, attempts)
+	return resp, nil
+}
+
+func (t *transport) roundTrip(req *http.Request, spanCtx context.Context, attempt int) (*http.Response, error) {
+	host := req.URL.Host
+	b := t.breaker(host)
+	probe := false
+	if b != nil {
+		var allowed bool
+		if allowed, probe = b.allow(); !allowed {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.hostTimeout(host))
+	r := req.Clone(ctx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+	injectHeaders(req.Context(), spanCtx, r.Header)
+
+	resp, err := t.base.RoundTrip(r)
+	// failures caused by the caller giving up are not held against the host
+	if b != nil {
+		if req.Context().Err() == nil {
+			b.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+		} else if probe {
+			b.release()
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// CloseIdleConnections is called by http.Client.CloseIdleConnections
+func (t *transport) CloseIdleConnections() {
+	if ci, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		ci.CloseIdleConnections()
+	}
+}
+
+func (t *transport) hostTimeout(host string) time.Duration {
+	if timeout, ok := t.hostTimeouts[host]; ok {
+		return timeout
+	}
+	return t.timeout
+}
+
+func (t *transport) breaker(host string) *breaker {
+	if t.breakerThreshold <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		b = newBreaker(t.breakerThreshold, t.breakerTimeout)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// backoff doubles the wait on every retry up to retryWaitMax, keeping half of it random
+func (t *transport) backoff(attempt int) time.Duration {
+	wait := t.retryWaitMin << (attempt - 1)
+	if wait <= 0 || wait > t.retryWaitMax {
+		wait = t.retryWaitMax
+	}
+	half := wait / 2
+	return half + rand.N(half+1)
+}
+
+// isRetryable allows retries for idempotent methods whose body can be replayed
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// traceContext unwraps the request context of a gin context, where the server span lives
+func traceContext(ctx context.Context) context.Context {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		return c.Request.Context()
+	}
+	return ctx
+}
+
+// injectHeaders propagates the client span and the request ID of the incoming request
+func injectHeaders(ctx context.Context, spanCtx context.Context, header http.Header) {
+	logger.Propagator.Inject(spanCtx, propagation.HeaderCarrier(header))
+
+	headerKey := requestid.HeaderKey
+	if headerKey == "" {
+		headerKey = defaultRequestIDHeader
+	}
+	if header.Get(headerKey) == "" {
+		if requestID := requestid.Get(ctx); requestID != "" {
+			header.Set(headerKey, requestID)
+		}
+	}
+}
+
+// cancelBody releases the attempt timeout once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
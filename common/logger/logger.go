@@ -54,6 +54,17 @@ type TracingConfig struct {
 	// TracerName: tracer name for otel.Tracer()
 	// Default: "default"
 	TracerName string
+
+	// Exporter: where finished spans are shipped
+	// If nil, spans are recorded but never exported
+	Exporter *ExporterConfig
+
+	// Sampler: which spans are recorded
+	// If nil, every span is sampled
+	Sampler *SamplerConfig
+
+	// Attributes: extra resource attributes, service.name is taken from ServiceName
+	Attributes map[string]string
 }
 
 type Config struct {
@@ -120,6 +131,7 @@ func initializeTracingConfig(cfg *TracingConfig) *TracingConfig {
 				trace.WithSampler(trace.AlwaysSample()),
 			)
 			otel.SetTracerProvider(globalTracerProvider)
+			otel.SetTextMapPropagator(Propagator)
 		}
 		cfg.TracerProvider = globalTracerProvider
 	}
@@ -395,6 +407,11 @@ func (l *Logger) LevelEnabled(levelText string) bool {
 	return l.inner.Level().Enabled(level)
 }
 
+// Enabled reports whether the level is enabled by both the logger and its cores
+func (l *Logger) Enabled(level zapcore.Level) bool {
+	return l.level.Enabled(level) && l.inner.Level().Enabled(level)
+}
+
 func RegisterCore(name string, coreFactory func(option Config) zapcore.Core) {
 	coreMap[name] = coreFactory
 }
@@ -687,12 +704,19 @@ func Setup(opts ...OptionFunc) {
 	for _, opt := range opts {
 		opt(&option)
 	}
+	if err := setupTracerProvider(option); err != nil {
+		log.Panic(err)
+	}
 	globalLogger = New(option)
 	globalTracerConfig = option.Tracing
 
 	Debugf("Added logger core: %s", strings.Join(lo.Keys(coreMap), ", "))
 
 	Infof("Global logger has been initialized with %d core", len(coreMap))
+
+	if option.Tracing != nil && option.Tracing.Enabled && option.Tracing.Exporter != nil {
+		Infof("Tracing spans are exported by %s exporter", option.Tracing.Exporter.Type)
+	}
 }
 
 // Panic logs PanicLevel message
@@ -972,6 +996,7 @@ func InjectTraceContext(ctx context.Context, traceIDHex, spanIDHex string) (cont
 		TraceID:    traceID,
 		SpanID:     spanID,
 		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
 	})
 
 	// Inject into context
@@ -265,3 +265,75 @@ func TestTracingDisabled(t *testing.T) {
 	// But logger won't add trace fields automatically
 	TInfo(ctx, "Tracing disabled test")
 }
+
+// TestTracingExporter tests spans shipped by the configured exporter
+func TestTracingExporter(t *testing.T) {
+	Setup(func(cfg *Config) {
+		cfg.Level = "info"
+		cfg.ServiceName = "test-service"
+		cfg.Tracing = &TracingConfig{
+			Enabled:    true,
+			Exporter:   &ExporterConfig{Type: ExporterMemory},
+			Attributes: map[string]string{"env": "test"},
+		}
+	})
+	MemoryExporter().Reset()
+
+	_, span := StartSpan(context.Background(), "exported")
+	span.End()
+
+	spans := MemoryExporter().GetSpans()
+	if len(spans) != 1 || spans[0].Name != "exported" {
+		t.Fatalf("Expected 1 exported span, got %d", len(spans))
+	}
+	if v, ok := spans[0].Resource.Set().Value("service.name"); !ok || v.AsString() != "test-service" {
+		t.Errorf("Expected service.name resource attribute, got %s", v.AsString())
+	}
+	if v, ok := spans[0].Resource.Set().Value("env"); !ok || v.AsString() != "test" {
+		t.Errorf("Expected env resource attribute, got %s", v.AsString())
+	}
+
+	if err := ShutdownTracing(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestTracingSampler tests the ratio and parent-based samplers
+func TestTracingSampler(t *testing.T) {
+	Setup(func(cfg *Config) {
+		cfg.Level = "info"
+		cfg.Tracing = &TracingConfig{
+			Enabled:  true,
+			Exporter: &ExporterConfig{Type: ExporterMemory},
+			Sampler:  &SamplerConfig{Type: SamplerRatio, Ratio: 0, ParentBased: true},
+		}
+	})
+	MemoryExporter().Reset()
+
+	// root span dropped by ratio 0
+	_, span := StartSpan(context.Background(), "root")
+	span.End()
+	if len(MemoryExporter().GetSpans()) != 0 {
+		t.Error("Expected root span to be dropped")
+	}
+
+	// sampled remote parent is followed
+	ctx, span, err := StartSpanWithRemoteParent(context.Background(), "child",
+		"0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331")
+	if err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+	if len(MemoryExporter().GetSpans()) != 1 || GetTraceID(ctx) != "0af7651916cd43dd8448eb211c80319c" {
+		t.Error("Expected child of sampled parent to be exported")
+	}
+
+	if _, err = NewTracerProvider("", &TracingConfig{Sampler: &SamplerConfig{Type: SamplerRatio, Ratio: 2}}); err == nil {
+		t.Error("Expected invalid ratio error")
+	}
+	if _, err = NewTracerProvider("", &TracingConfig{Exporter: &ExporterConfig{Type: "unknown"}}); err == nil {
+		t.Error("Expected unsupported exporter error")
+	}
+
+	_ = ShutdownTracing(context.Background())
+}
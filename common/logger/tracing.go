@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Span exporter types
+const (
+	ExporterOtlpHttp = "otlp_http"
+	ExporterOtlpGrpc = "otlp_grpc"
+	ExporterStdout   = "stdout"
+	ExporterMemory   = "memory"
+)
+
+// Sampler types
+const (
+	SamplerAlwaysOn  = "always_on"
+	SamplerAlwaysOff = "always_off"
+	SamplerRatio     = "ratio"
+)
+
+// ExporterConfig configures where finished spans are shipped
+type ExporterConfig struct {
+	// Type: otlp_http, otlp_grpc, stdout, memory or a registered custom exporter
+	Type string
+	// Endpoint: host:port of the collector, the OTLP exporters fall back to OTEL_EXPORTER_OTLP_* env if empty
+	Endpoint string
+	// URLPath: url path of the OTLP HTTP exporter, default /v1/traces
+	URLPath string
+	// Insecure disables TLS of the OTLP exporters
+	Insecure bool
+	// Headers sent with every OTLP export request
+	Headers map[string]string
+	// Compression: gzip or none
+	Compression string
+	// Timeout of an export request in seconds
+	Timeout int
+	// PrettyPrint indents the JSON written by the stdout exporter
+	PrettyPrint bool
+}
+
+// SamplerConfig decides which spans are recorded
+type SamplerConfig struct {
+	// Type: always_on, always_off or ratio, default always_on
+	Type string
+	// Ratio: fraction of traces sampled by the ratio sampler
+	Ratio float64
+	// ParentBased follows the sampling decision of the parent span when there is one
+	ParentBased bool
+}
+
+type exporterFactory func(option ExporterConfig) (trace.SpanExporter, error)
+
+var (
+	exporterMap    = make(map[string]exporterFactory)
+	memoryExporter = tracetest.NewInMemoryExporter()
+	// Propagator carries W3C trace context and baggage across process boundaries
+	Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+)
+
+func init() {
+	RegisterExporter(ExporterOtlpHttp, otlpHttpExporterFactory)
+	RegisterExporter(ExporterOtlpGrpc, otlpGrpcExporterFactory)
+	RegisterExporter(ExporterStdout, stdoutExporterFactory)
+	RegisterExporter(ExporterMemory, func(option ExporterConfig) (trace.SpanExporter, error) {
+		return memoryExporter, nil
+	})
+}
+
+// RegisterExporter adds a span exporter selectable by logger.tracing.exporter.type
+func RegisterExporter(name string, factory func(option ExporterConfig) (trace.SpanExporter, error)) {
+	exporterMap[name] = factory
+}
+
+// MemoryExporter returns the exporter used by the memory type, for asserting spans in tests
+func MemoryExporter() *tracetest.InMemoryExporter {
+	return memoryExporter
+}
+
+func otlpHttpExporterFactory(option ExporterConfig) (trace.SpanExporter, error) {
+	var opts []otlptracehttp.Option
+	if option.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(option.Endpoint))
+	}
+	if option.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(option.URLPath))
+	}
+	if option.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(option.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(option.Headers))
+	}
+	if option.Compression == "gzip" {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	if option.Timeout > 0 {
+		opts = append(opts, otlptracehttp.WithTimeout(time.Duration(option.Timeout)*time.Second))
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+func otlpGrpcExporterFactory(option ExporterConfig) (trace.SpanExporter, error) {
+	var opts []otlptracegrpc.Option
+	if option.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(option.Endpoint))
+	}
+	if option.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(option.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(option.Headers))
+	}
+	if option.Compression == "gzip" {
+		opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+	}
+	if option.Timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(time.Duration(option.Timeout)*time.Second))
+	}
+	return otlptracegrpc.New(context.Background(), opts...)
+}
+
+func stdoutExporterFactory(option ExporterConfig) (trace.SpanExporter, error) {
+	opts := []stdouttrace.Option{stdouttrace.WithWriter(os.Stdout)}
+	if option.PrettyPrint {
+		opts = append(opts, stdouttrace.WithPrettyPrint())
+	}
+	return stdouttrace.New(opts...)
+}
+
+func newSampler(option *SamplerConfig) (trace.Sampler, error) {
+	if option == nil {
+		return trace.AlwaysSample(), nil
+	}
+
+	var sampler trace.Sampler
+	switch option.Type {
+	case "", SamplerAlwaysOn:
+		sampler = trace.AlwaysSample()
+	case SamplerAlwaysOff:
+		sampler = trace.NeverSample()
+	case SamplerRatio:
+		if option.Ratio < 0 || option.Ratio > 1 {
+			return nil, fmt.Errorf("invalid sampler ratio %v, must be between 0 and 1", option.Ratio)
+		}
+		sampler = trace.TraceIDRatioBased(option.Ratio)
+	default:
+		return nil, fmt.Errorf("unsupported sampler type: %s", option.Type)
+	}
+
+	if option.ParentBased {
+		sampler = trace.ParentBased(sampler)
+	}
+	return sampler, nil
+}
+
+func newResource(serviceName string, attributes map[string]string) (*resource.Resource, error) {
+	attrs := make([]attribute.KeyValue, 0, len(attributes)+1)
+	if serviceName != "" {
+		attrs = append(attrs, semconv.ServiceName(serviceName))
+	}
+	for k, v := range attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	return resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...))
+}
+
+// NewTracerProvider creates a TracerProvider from the tracing configuration,
+// spans are dropped if no exporter is configured
+func NewTracerProvider(serviceName string, cfg *TracingConfig) (*trace.TracerProvider, error) {
+	sampler, err := newSampler(cfg.Sampler)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(serviceName, cfg.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []trace.TracerProviderOption{
+		trace.WithSampler(sampler),
+		trace.WithResource(res),
+	}
+
+	if cfg.Exporter != nil && cfg.Exporter.Type != "" {
+		factory, ok := exporterMap[cfg.Exporter.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported span exporter type: %s", cfg.Exporter.Type)
+		}
+		exporter, err := factory(*cfg.Exporter)
+		if err != nil {
+			return nil, err
+		}
+
+		// export synchronously in memory so that tests see spans as soon as they end
+		if cfg.Exporter.Type == ExporterMemory {
+			opts = append(opts, trace.WithSyncer(exporter))
+		} else {
+			opts = append(opts, trace.WithBatcher(exporter))
+		}
+	}
+
+	return trace.NewTracerProvider(opts...), nil
+}
+
+// setupTracerProvider replaces the global TracerProvider with one built from the configuration
+func setupTracerProvider(option Config) error {
+	cfg := option.Tracing
+	if cfg == nil || !cfg.Enabled || cfg.TracerProvider != nil {
+		return nil
+	}
+
+	tp, err := NewTracerProvider(option.ServiceName, cfg)
+	if err != nil {
+		return err
+	}
+
+	if globalTracerProvider != nil {
+		_ = globalTracerProvider.Shutdown(context.Background())
+	}
+	globalTracerProvider = tp
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator)
+	cfg.TracerProvider = tp
+	return nil
+}
+
+// IsTracingEnabled reports whether the global logger has tracing enabled
+func IsTracingEnabled() bool {
+	return globalLogger != nil && globalLogger.tracingConfig != nil && globalLogger.tracingConfig.Enabled
+}
+
+// ShutdownTracing flushes the pending spans and shuts down the global TracerProvider
+func ShutdownTracing(ctx context.Context) error {
+	if globalTracerProvider == nil {
+		return nil
+	}
+
+	err := errors.Join(globalTracerProvider.ForceFlush(ctx), globalTracerProvider.Shutdown(ctx))
+	globalTracerProvider = nil
+	return err
+}
@@ -2,6 +2,8 @@ package mailx
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -126,3 +128,26 @@ func Setup() {
 	logger.Infof("Mail client pool has been initialized with %d clients, clients: %s",
 		len(clientPool), strings.Join(lo.Keys(clientPool), ", "))
 }
+
+// Ping resets the SMTP session of every client in the pool
+func Ping(_ context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		if err := client.Reset(); err != nil {
+			errs = append(errs, fmt.Errorf("mail [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every client in the pool
+func Close() error {
+	var errs []error
+	for name, client := range clientPool {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("mail [%s]: %w", name, err))
+		}
+	}
+	logger.Infof("Mail client pool has been closed")
+	return errors.Join(errs...)
+}
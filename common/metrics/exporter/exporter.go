@@ -0,0 +1,29 @@
+// Package exporter mounts the Prometheus endpoint of the metrics registry, apart from metrics,
+// which is imported by the redis clients the security validators depend on
+package exporter
+
+import (
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/metrics"
+	"github.com/gfa-inc/gfa/middlewares/accesslog"
+	"github.com/gfa-inc/gfa/middlewares/security"
+	"github.com/gfa-inc/gfa/utils/httpmethod"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Setup mounts the Prometheus endpoint, permitted by the security and access log middlewares
+func Setup(r *gin.RouterGroup) {
+	if !metrics.Enabled() {
+		return
+	}
+
+	path := metrics.Path()
+	r.GET(path, gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		Registry: metrics.Registry,
+	})))
+	security.PermitRoute(path, httpmethod.MethodGet)
+	accesslog.PermitRoute(path, httpmethod.MethodGet)
+
+	logger.Infof("Metrics endpoint enabled, path: %s", path)
+}
@@ -0,0 +1,34 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/metrics"
+	"github.com/gfa-inc/gfa/middlewares/security"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup(t *testing.T) {
+	config.Setup()
+	defer config.Setup()
+	logger.Setup()
+	config.SetDefault("metrics.enable", true)
+	config.SetDefault("security.api_key.lookup", "header:X-Api-Key")
+
+	engine := gin.New()
+	engine.Use(metrics.Metrics(), security.Security())
+	Setup(&engine.RouterGroup)
+	engine.GET("/orders", func(c *gin.Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, metrics.DefaultPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+	assert.False(t, security.RequiresAuth(metrics.DefaultPath, http.MethodGet))
+	assert.True(t, security.RequiresAuth("/orders", http.MethodGet))
+}
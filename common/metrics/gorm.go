@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const gormStartKey = "gfa:metrics_start"
+
+var (
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"datasource", "operation"})
+	dbQueryErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Total number of failed database queries, record not found excluded.",
+	}, []string{"datasource", "operation"})
+)
+
+// GormPlugin records the duration of every statement executed by a named datasource
+type GormPlugin struct {
+	datasource string
+}
+
+func NewGormPlugin(datasource string) *GormPlugin {
+	return &GormPlugin{datasource: datasource}
+}
+
+func (p *GormPlugin) Name() string {
+	return "gfa:metrics"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, processor := range processors {
+		if err := processor.before("gfa:metrics_before_"+processor.operation, p.before); err != nil {
+			return err
+		}
+		if err := processor.after("gfa:metrics_after_"+processor.operation, p.after(processor.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		dbQueryDuration.WithLabelValues(p.datasource, operation).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			dbQueryErrorsTotal.WithLabelValues(p.datasource, operation).Inc()
+		}
+	}
+}
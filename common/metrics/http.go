@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UnmatchedPath labels requests that match no registered route, keeping the label cardinality bounded
+const UnmatchedPath = "<unmatched>"
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests.",
+	}, []string{"method", "path", "status"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path", "status"})
+)
+
+// Metrics records the count and latency of every request by route, method and status
+func Metrics() gin.HandlerFunc {
+	logger.Info("Metrics middleware enabled")
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = UnmatchedPath
+		}
+		method := c.Request.Method
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequestsTotal.WithLabelValues(method, path, status).Inc()
+		httpRequestDuration.WithLabelValues(method, path, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+var kafkaCollector = newKafkaStatsCollector()
+
+func newKafkaDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, []string{"client"}, nil)
+}
+
+var (
+	kafkaReaderMessagesDesc   = newKafkaDesc("kafka_reader_messages_total", "Total number of messages read.")
+	kafkaReaderBytesDesc      = newKafkaDesc("kafka_reader_bytes_total", "Total number of message bytes read.")
+	kafkaReaderErrorsDesc     = newKafkaDesc("kafka_reader_errors_total", "Total number of reader errors.")
+	kafkaReaderRebalancesDesc = newKafkaDesc("kafka_reader_rebalances_total", "Total number of consumer group rebalances.")
+	kafkaReaderTimeoutsDesc   = newKafkaDesc("kafka_reader_timeouts_total", "Total number of reader timeouts.")
+	kafkaReaderLagDesc        = newKafkaDesc("kafka_reader_lag", "Current lag of the reader.")
+	kafkaWriterWritesDesc     = newKafkaDesc("kafka_writer_writes_total", "Total number of write requests.")
+	kafkaWriterMessagesDesc   = newKafkaDesc("kafka_writer_messages_total", "Total number of messages written.")
+	kafkaWriterBytesDesc      = newKafkaDesc("kafka_writer_bytes_total", "Total number of message bytes written.")
+	kafkaWriterErrorsDesc     = newKafkaDesc("kafka_writer_errors_total", "Total number of writer errors.")
+	kafkaWriterRetriesDesc    = newKafkaDesc("kafka_writer_retries_total", "Total number of write retries.")
+)
+
+// kafkaStatsCollector exposes the stats of the registered readers and writers.
+// kafka-go resets its counters on every Stats call, so the totals are accumulated here.
+type kafkaStatsCollector struct {
+	mu           sync.Mutex
+	readers      map[string]*kafka.Reader
+	writers      map[string]*kafka.Writer
+	readerTotals map[string]*kafka.ReaderStats
+	writerTotals map[string]*kafka.WriterStats
+}
+
+func newKafkaStatsCollector() *kafkaStatsCollector {
+	return &kafkaStatsCollector{
+		readers:      make(map[string]*kafka.Reader),
+		writers:      make(map[string]*kafka.Writer),
+		readerTotals: make(map[string]*kafka.ReaderStats),
+		writerTotals: make(map[string]*kafka.WriterStats),
+	}
+}
+
+// RegisterKafkaReader exposes the stats of a named reader, registering the same name again replaces the reader
+func RegisterKafkaReader(name string, reader *kafka.Reader) {
+	kafkaCollector.mu.Lock()
+	defer kafkaCollector.mu.Unlock()
+	kafkaCollector.readers[name] = reader
+	kafkaCollector.readerTotals[name] = &kafka.ReaderStats{}
+}
+
+// RegisterKafkaWriter exposes the stats of a named writer, registering the same name again replaces the writer
+func RegisterKafkaWriter(name string, writer *kafka.Writer) {
+	kafkaCollector.mu.Lock()
+	defer kafkaCollector.mu.Unlock()
+	kafkaCollector.writers[name] = writer
+	kafkaCollector.writerTotals[name] = &kafka.WriterStats{}
+}
+
+func (k *kafkaStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		kafkaReaderMessagesDesc, kafkaReaderBytesDesc, kafkaReaderErrorsDesc, kafkaReaderRebalancesDesc,
+		kafkaReaderTimeoutsDesc, kafkaReaderLagDesc, kafkaWriterWritesDesc, kafkaWriterMessagesDesc,
+		kafkaWriterBytesDesc, kafkaWriterErrorsDesc, kafkaWriterRetriesDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (k *kafkaStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for name, reader := range k.readers {
+		stats := reader.Stats()
+		total := k.readerTotals[name]
+		total.Messages += stats.Messages
+		total.Bytes += stats.Bytes
+		total.Errors += stats.Errors
+		total.Rebalances += stats.Rebalances
+		total.Timeouts += stats.Timeouts
+
+		ch <- prometheus.MustNewConstMetric(kafkaReaderMessagesDesc, prometheus.CounterValue, float64(total.Messages), name)
+		ch <- prometheus.MustNewConstMetric(kafkaReaderBytesDesc, prometheus.CounterValue, float64(total.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(kafkaReaderErrorsDesc, prometheus.CounterValue, float64(total.Errors), name)
+		ch <- prometheus.MustNewConstMetric(kafkaReaderRebalancesDesc, prometheus.CounterValue, float64(total.Rebalances), name)
+		ch <- prometheus.MustNewConstMetric(kafkaReaderTimeoutsDesc, prometheus.CounterValue, float64(total.Timeouts), name)
+		ch <- prometheus.MustNewConstMetric(kafkaReaderLagDesc, prometheus.GaugeValue, float64(stats.Lag), name)
+	}
+
+	for name, writer := range k.writers {
+		stats := writer.Stats()
+		total := k.writerTotals[name]
+		total.Writes += stats.Writes
+		total.Messages += stats.Messages
+		total.Bytes += stats.Bytes
+		total.Errors += stats.Errors
+		total.Retries += stats.Retries
+
+		ch <- prometheus.MustNewConstMetric(kafkaWriterWritesDesc, prometheus.CounterValue, float64(total.Writes), name)
+		ch <- prometheus.MustNewConstMetric(kafkaWriterMessagesDesc, prometheus.CounterValue, float64(total.Messages), name)
+		ch <- prometheus.MustNewConstMetric(kafkaWriterBytesDesc, prometheus.CounterValue, float64(total.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(kafkaWriterErrorsDesc, prometheus.CounterValue, float64(total.Errors), name)
+		ch <- prometheus.MustNewConstMetric(kafkaWriterRetriesDesc, prometheus.CounterValue, float64(total.Retries), name)
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const DefaultPath = "/metrics"
+
+// Registry holds every metric exposed by the metrics endpoint, custom collectors can be registered on it
+var Registry = prometheus.NewRegistry()
+
+type Config struct {
+	Enable bool
+	Path   string
+}
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		dbQueryDuration,
+		dbQueryErrorsTotal,
+		redisCommandDuration,
+		redisCommandErrorsTotal,
+		kafkaCollector,
+	)
+}
+
+func getConfig() Config {
+	option := Config{
+		Enable: true,
+		Path:   DefaultPath,
+	}
+	err := config.UnmarshalKey("metrics", &option)
+	if err != nil {
+		logger.Panic(err)
+	}
+	return option
+}
+
+// Enabled reports whether the metrics section is configured and not disabled
+func Enabled() bool {
+	return config.Get("metrics") != nil && getConfig().Enable
+}
+
+// Path returns the path of the Prometheus endpoint
+func Path() string {
+	return getConfig().Path
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	config.Setup(config.WithPath("../../"))
+	config.SetDefault("metrics.enable", true)
+	logger.Setup()
+
+	assert.True(t, Enabled())
+
+	engine := gin.New()
+	engine.Use(Metrics(), middlewares.OnError())
+	r := engine.Group(config.GetString("server.base_path"))
+	r.GET("/hello/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/param", func(c *gin.Context) {
+		_ = c.Error(core.NewParamErr("id is required"))
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, config.GetString("server.base_path")+path, nil))
+		return w
+	}
+	serve("/hello/1")
+	serve("/hello/2")
+	serve("/missing")
+	serve("/param")
+
+	route := config.GetString("server.base_path") + "/hello/:id"
+	assert.Equal(t, float64(2), testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, route, "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, UnmatchedPath, "404")))
+	// the status written by OnError is recorded
+	route = config.GetString("server.base_path") + "/param"
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, route, "400")))
+}
+
+func TestRedisHook(t *testing.T) {
+	hook := NewRedisHook("test")
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "get" {
+			return redis.Nil
+		}
+		return errors.New("connection refused")
+	})
+
+	_ = process(context.Background(), redis.NewStringCmd(context.Background(), "get", "key"))
+	_ = process(context.Background(), redis.NewStatusCmd(context.Background(), "set", "key", "value"))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(redisCommandDuration))
+	assert.Equal(t, float64(0), testutil.ToFloat64(redisCommandErrorsTotal.WithLabelValues("test", "get")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(redisCommandErrorsTotal.WithLabelValues("test", "set")))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+const pipelineCommand = "pipeline"
+
+var (
+	redisCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_command_duration_seconds",
+		Help:    "Redis command latency in seconds.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"client", "command"})
+	redisCommandErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_errors_total",
+		Help: "Total number of failed redis commands, nil replies excluded.",
+	}, []string{"client", "command"})
+)
+
+// RedisHook records the latency of every command sent by a named client
+type RedisHook struct {
+	client string
+}
+
+func NewRedisHook(client string) *RedisHook {
+	return &RedisHook{client: client}
+}
+
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.observe(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.observe(pipelineCommand, start, err)
+		return err
+	}
+}
+
+func (h *RedisHook) observe(command string, start time.Time, err error) {
+	redisCommandDuration.WithLabelValues(h.client, command).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		redisCommandErrorsTotal.WithLabelValues(h.client, command).Inc()
+	}
+}
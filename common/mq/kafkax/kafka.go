@@ -1,10 +1,15 @@
 package kafkax
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/metrics"
 	"github.com/gfa-inc/gfa/utils/ptr"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl/plain"
@@ -74,6 +79,9 @@ func NewConsumerClient(option ConsumerConfig) *kafka.Reader {
 	})
 
 	reader := kafka.NewReader(cfg)
+	if metrics.Enabled() {
+		metrics.RegisterKafkaReader(option.Name, reader)
+	}
 
 	logger.Debugf("Consume to kafka [%s]", option.Name)
 
@@ -100,6 +108,9 @@ func NewProducerClient(option ProducerConfig) *kafka.Writer {
 	})
 
 	writer := kafka.NewWriter(cfg)
+	if metrics.Enabled() {
+		metrics.RegisterKafkaWriter(option.Name, writer)
+	}
 
 	logger.Debugf("Produce to kafka [%s]", option.Name)
 
@@ -231,3 +242,66 @@ func fillMechanism(saslConfig SaslConfig) *kafka.Dialer {
 
 	return &dialer
 }
+
+// Ping dials the brokers of every consumer and producer in the pools
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range consumerClientPool {
+		cfg := client.Config()
+		if err := pingBrokers(ctx, cfg.Dialer, cfg.Brokers); err != nil {
+			errs = append(errs, fmt.Errorf("kafka consumer [%s]: %w", name, err))
+		}
+	}
+	for name, client := range producerClientPool {
+		if err := pingBrokers(ctx, writerDialer(client), strings.Split(client.Addr.String(), ",")); err != nil {
+			errs = append(errs, fmt.Errorf("kafka producer [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every consumer and producer in the pools
+func Close() error {
+	var errs []error
+	for name, client := range consumerClientPool {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("kafka consumer [%s]: %w", name, err))
+		}
+	}
+	for name, client := range producerClientPool {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("kafka producer [%s]: %w", name, err))
+		}
+	}
+	logger.Infof("Kafka client pools have been closed")
+	return errors.Join(errs...)
+}
+
+// pingBrokers succeeds as soon as one of the brokers accepts a connection
+func pingBrokers(ctx context.Context, dialer *kafka.Dialer, brokers []string) error {
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+
+	var errs []error
+	for _, broker := range brokers {
+		conn, err := dialer.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_ = conn.Close()
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// writerDialer rebuilds a dialer carrying the SASL and TLS settings of the writer transport
+func writerDialer(writer *kafka.Writer) *kafka.Dialer {
+	dialer := *kafka.DefaultDialer
+	if transport, ok := writer.Transport.(*kafka.Transport); ok {
+		dialer.SASLMechanism = transport.SASL
+		dialer.TLS = transport.TLS
+	}
+	return &dialer
+}
@@ -1,7 +1,19 @@
 package mq
 
-import "github.com/gfa-inc/gfa/common/mq/kafkax"
+import (
+	"context"
+
+	"github.com/gfa-inc/gfa/common/mq/kafkax"
+)
 
 func Setup() {
 	kafkax.Setup()
 }
+
+func Ping(ctx context.Context) error {
+	return kafkax.Ping(ctx)
+}
+
+func Close() error {
+	return kafkax.Close()
+}
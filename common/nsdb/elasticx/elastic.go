@@ -3,6 +3,8 @@ package elasticx
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -98,3 +100,30 @@ func GetClient(name string) *elasticsearch.TypedClient {
 func PutClient(name string, client *elasticsearch.TypedClient) {
 	clientPool[name] = client
 }
+
+// Ping pings every client in the pool
+func Ping(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		ok, err := client.Ping().Do(ctx)
+		if err == nil && !ok {
+			err = errors.New("ping failed")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("elastic [%s]: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every client in the pool
+func Close(ctx context.Context) error {
+	var errs []error
+	for name, client := range clientPool {
+		if err := client.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("elastic [%s]: %w", name, err))
+		}
+	}
+	logger.Infof("Elastic client pool has been closed")
+	return errors.Join(errs...)
+}
@@ -1,7 +1,19 @@
 package nsdb
 
-import "github.com/gfa-inc/gfa/common/nsdb/elasticx"
+import (
+	"context"
+
+	"github.com/gfa-inc/gfa/common/nsdb/elasticx"
+)
 
 func Setup() {
 	elasticx.Setup()
 }
+
+func Ping(ctx context.Context) error {
+	return elasticx.Ping(ctx)
+}
+
+func Close(ctx context.Context) error {
+	return elasticx.Close(ctx)
+}
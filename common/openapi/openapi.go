@@ -0,0 +1,314 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares/accesslog"
+	"github.com/gfa-inc/gfa/middlewares/security"
+	"github.com/gfa-inc/gfa/utils/httpmethod"
+	"github.com/gin-gonic/gin"
+	"go.yaml.in/yaml/v3"
+)
+
+const DefaultPath = "/openapi"
+
+//go:embed ui.html
+var uiHTML string
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiHTML))
+
+type Config struct {
+	Enable bool
+	// Path serves the UI, the document is served at Path.json and Path.yaml, default /openapi
+	Path        string
+	Title       string
+	Version     string
+	Description string
+	Servers     []Server
+}
+
+// Generate builds the document of the routes, handlers built by core.Handle are described by their types
+func Generate(routes gin.RoutesInfo, option Config) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       option.Title,
+			Version:     option.Version,
+			Description: option.Description,
+		},
+		Servers: option.Servers,
+		Paths:   make(map[string]map[string]Operation),
+	}
+
+	schemes, schemeKeys := securitySchemes()
+	if len(schemes) > 0 {
+		doc.Components.SecuritySchemes = schemes
+		for _, name := range sortedKeys(schemes) {
+			doc.Security = append(doc.Security, map[string][]string{name: {}})
+		}
+	}
+
+	for _, route := range routes {
+		p := toPath(route.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(map[string]Operation)
+		}
+
+		op := Operation{
+			OperationID: operationID(route.Method, route.Path),
+			Responses: map[string]Response{
+				"200": {Description: "OK"},
+			},
+		}
+		if meta, ok := core.DescribeHandler(route.HandlerFunc); ok {
+			g.describe(&op, route, meta)
+		}
+		if len(schemes) > 0 {
+			requirements := []map[string][]string{}
+			if security.RequiresAuth(route.Path, route.Method) {
+				requirements = securityRequirements(route, schemeKeys)
+			}
+			if requirements != nil {
+				op.Security = &requirements
+			}
+		}
+		doc.Paths[p][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func (g *generator) describe(op *Operation, route gin.RouteInfo, meta core.HandlerMeta) {
+	op.Summary = meta.Summary
+	op.Description = meta.Description
+	op.Tags = meta.Tags
+	op.Deprecated = meta.Deprecated
+
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields(meta.Request) {
+		param := Parameter{Schema: g.schemaOf(f.Type)}
+		required := applyRules(param.Schema, f)
+		if name, ok := tagName(f, "uri"); ok && name != "" {
+			param.Name, param.In, param.Required = name, "path", true
+		} else if name, ok = tagName(f, "header"); ok && name != "" {
+			param.Name, param.In, param.Required = name, "header", required
+		} else if name, ok = tagName(f, "form"); ok && name != "" && name != "-" {
+			param.Name, param.In, param.Required = name, "query", required
+		} else if name, ok = jsonName(f); ok {
+			body.Properties[name] = param.Schema
+			if required {
+				body.Required = append(body.Required, name)
+			}
+			continue
+		} else {
+			continue
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	if len(body.Properties) > 0 && hasBody(route.Method) {
+		op.RequestBody = &RequestBody{
+			Required: len(body.Required) > 0,
+			Content:  map[string]MediaType{"application/json": {Schema: body}},
+		}
+	}
+
+	result := g.schemaOf(meta.Response)
+	if core.DefaultFormat() == core.FormatProblem {
+		problem := map[string]MediaType{core.ProblemContentType: {Schema: g.schemaOf(reflect.TypeFor[core.Problem]())}}
+		op.Responses["200"] = Response{Description: "OK", Content: jsonContent(result)}
+		op.Responses["400"] = Response{Description: "Invalid parameters", Content: problem}
+		op.Responses["default"] = Response{Description: "Error", Content: problem}
+		return
+	}
+
+	fieldErrors := &Schema{Type: "array", Items: g.schemaOf(reflect.TypeFor[core.FieldError]())}
+	op.Responses["200"] = Response{Description: "OK", Content: jsonContent(envelope(result))}
+	op.Responses["400"] = Response{Description: "Invalid parameters", Content: jsonContent(envelope(fieldErrors))}
+	op.Responses["default"] = Response{Description: "Error", Content: jsonContent(envelope(&Schema{Type: "null"}))}
+}
+
+// envelope describes core.Response wrapping data
+func envelope(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"code":    {Type: "string"},
+			"msg":     {Type: "string"},
+			"data":    data,
+			"traceId": {Type: "string"},
+		},
+		Required: []string{"success", "code"},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// securitySchemes names the schemes of the security validators, suffixing the location when a validator has several,
+// the names of the schemes of each validator are returned too
+func securitySchemes() (map[string]SecurityScheme, map[string][]string) {
+	schemes := make(map[string]SecurityScheme)
+	keys := make(map[string][]string)
+	for name, list := range security.Schemes() {
+		for _, s := range list {
+			key := name
+			if len(list) > 1 {
+				key = name + "_" + s.In
+				if s.In == "" {
+					key = name + "_" + s.Scheme
+				}
+			}
+			schemes[key] = SecurityScheme{
+				Type:         s.Type,
+				Scheme:       s.Scheme,
+				BearerFormat: s.BearerFormat,
+				In:           s.In,
+				Name:         s.Name,
+			}
+			keys[name] = append(keys[name], key)
+		}
+	}
+	return schemes, keys
+}
+
+// securityRequirements describes the security policy of a route, each validator of an any policy is an alternative
+// requirement, the validators of an all policy are combined into one requirement, the schemes of a validator being
+// alternatives, nil if no validator of the policy is described
+func securityRequirements(route gin.RouteInfo, schemeKeys map[string][]string) []map[string][]string {
+	names, match := security.RoutePolicy(route.Path, route.Method)
+	if match != security.MatchAll {
+		var requirements []map[string][]string
+		for _, name := range names {
+			for _, key := range schemeKeys[name] {
+				requirements = append(requirements, map[string][]string{key: {}})
+			}
+		}
+		return requirements
+	}
+
+	requirements := []map[string][]string{{}}
+	for _, name := range names {
+		if len(schemeKeys[name]) == 0 {
+			continue
+		}
+		combined := make([]map[string][]string, 0, len(requirements)*len(schemeKeys[name]))
+		for _, requirement := range requirements {
+			for _, key := range schemeKeys[name] {
+				r := maps.Clone(requirement)
+				r[key] = []string{}
+				combined = append(combined, r)
+			}
+		}
+		requirements = combined
+	}
+	if len(requirements[0]) == 0 {
+		return nil
+	}
+	return requirements
+}
+
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// toPath converts gin parameters like :id and *path to {id} and {path}
+func toPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(route, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Setup serves the document generated from the routes of the engine and its UI under r,
+// the document is generated on the first request once every controller has registered its routes
+func Setup(engine *gin.Engine, r *gin.RouterGroup) {
+	option := Config{
+		Path:    DefaultPath,
+		Title:   config.GetString("name"),
+		Version: "1.0.0",
+	}
+	err := config.UnmarshalKey("openapi", &option)
+	if err != nil {
+		logger.Panic(err)
+	}
+	if !option.Enable {
+		logger.Debug("OpenAPI document disabled")
+		return
+	}
+	if option.Title == "" {
+		option.Title = "API"
+	}
+
+	jsonPath, yamlPath := option.Path+".json", option.Path+".yaml"
+	ownPaths := []string{r.BasePath() + option.Path, r.BasePath() + jsonPath, r.BasePath() + yamlPath}
+	document := sync.OnceValue(func() *Document {
+		routes := slices.DeleteFunc(engine.Routes(), func(route gin.RouteInfo) bool {
+			return slices.Contains(ownPaths, route.Path)
+		})
+		return Generate(routes, option)
+	})
+
+	r.GET(jsonPath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, document())
+	})
+	r.GET(yamlPath, func(c *gin.Context) {
+		out, err := yaml.Marshal(document())
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml; charset=utf-8", out)
+	})
+	r.GET(option.Path, func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = uiTemplate.Execute(c.Writer, map[string]string{
+			"Title":   option.Title,
+			"SpecURL": r.BasePath() + jsonPath,
+		})
+	})
+	for _, p := range []string{option.Path, jsonPath, yamlPath} {
+		security.PermitRoute(p, httpmethod.MethodGet)
+		accesslog.PermitRoute(p, httpmethod.MethodGet)
+	}
+
+	logger.Infof("OpenAPI document enabled, please visit http://%s%s to view the API documentation",
+		config.GetString("server.addr"), r.BasePath()+option.Path)
+}
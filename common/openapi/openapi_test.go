@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares/security"
+	"github.com/gfa-inc/gfa/utils/httpmethod"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type listUsersReq struct {
+	Tenant   string `header:"X-Tenant-ID" binding:"required"`
+	Status   string `form:"status" binding:"omitempty,oneof=active locked"`
+	PageSize int    `form:"pageSize,default=20" binding:"max=100"`
+}
+
+type createUserReq struct {
+	Group string   `uri:"group"`
+	Name  string   `json:"name" binding:"required,max=32"`
+	Email string   `json:"email" binding:"omitempty,email"`
+	Tags  []string `json:"tags" binding:"dive,required"`
+}
+
+type user struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Manager   *user     `json:"manager,omitempty"`
+}
+
+func listUsers(ctx context.Context, req listUsersReq) (core.PaginatedData[[]user], error) {
+	return core.Paginated([]user{}, 0), nil
+}
+
+func createUser(ctx context.Context, req createUserReq) (*user, error) {
+	return &user{}, nil
+}
+
+func TestGenerate(t *testing.T) {
+	engine := gin.New()
+	api := engine.Group("/api/v1")
+	api.GET("/users", core.Handle(listUsers, core.WithSummary("List users"), core.WithTags("user")))
+	api.POST("/groups/:group/users", core.Handle(createUser))
+	api.GET("/ping", func(c *gin.Context) {})
+
+	doc := Generate(engine.Routes(), Config{Title: "test", Version: "1.0.0"})
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Empty(t, doc.Security)
+
+	list := doc.Paths["/api/v1/users"]["get"]
+	assert.Equal(t, "getApiV1Users", list.OperationID)
+	assert.Equal(t, "List users", list.Summary)
+	assert.Equal(t, []string{"user"}, list.Tags)
+	assert.Len(t, list.Parameters, 3)
+	assert.Equal(t, Parameter{Name: "X-Tenant-ID", In: "header", Required: true, Schema: &Schema{Type: "string"}}, list.Parameters[0])
+	assert.Equal(t, []any{"active", "locked"}, list.Parameters[1].Schema.Enum)
+	assert.Equal(t, int64(20), list.Parameters[2].Schema.Default)
+	assert.Equal(t, float64(100), *list.Parameters[2].Schema.Maximum)
+
+	data := list.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	assert.Equal(t, "array", data.Properties["list"].Type)
+	assert.Equal(t, "#/components/schemas/user", data.Properties["list"].Items.Ref)
+	assert.Equal(t, "integer", data.Properties["total"].Type)
+
+	create := doc.Paths["/api/v1/groups/{group}/users"]["post"]
+	assert.Equal(t, Parameter{Name: "group", In: "path", Required: true, Schema: &Schema{Type: "string"}}, create.Parameters[0])
+	body := create.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"name"}, body.Required)
+	assert.Equal(t, 32, *body.Properties["name"].MaxLength)
+	assert.Equal(t, "email", body.Properties["email"].Format)
+	assert.Equal(t, "#/components/schemas/FieldError",
+		create.Responses["400"].Content["application/json"].Schema.Properties["data"].Items.Ref)
+
+	userSchema := doc.Components.Schemas["user"]
+	assert.Equal(t, "date-time", userSchema.Properties["createdAt"].Format)
+	assert.Equal(t, "#/components/schemas/user", userSchema.Properties["manager"].Ref)
+
+	ping := doc.Paths["/api/v1/ping"]["get"]
+	assert.Equal(t, "OK", ping.Responses["200"].Description)
+	assert.Nil(t, ping.Responses["200"].Content)
+}
+
+func TestSetup(t *testing.T) {
+	config.Setup()
+	config.SetDefault("openapi.enable", true)
+	defer config.Setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+
+	engine := gin.New()
+	api := engine.Group("/api/v1")
+	Setup(engine, api)
+	api.POST("/groups/:group/users", core.Handle(createUser))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	var doc map[string]any
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, Version, doc["openapi"])
+	paths := doc["paths"].(map[string]any)
+	assert.Len(t, paths, 1)
+	assert.Contains(t, paths, "/api/v1/groups/{group}/users")
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.yaml", nil))
+	assert.Contains(t, w.Body.String(), "openapi: 3.1.0")
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi", nil))
+	assert.Contains(t, w.Body.String(), `url: "\/api\/v1\/openapi.json"`)
+}
+
+func TestSecuritySchemes(t *testing.T) {
+	config.Setup()
+	config.SetDefault("security.jwt.private_key", "secret")
+	config.SetDefault("security.api_key.lookup", "header:X-Api-Key,query:api_key")
+	defer config.Setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	security.WithPolicy(security.Policy{
+		Path:       "/reports",
+		Validators: []string{security.DefaultJWTValidatorName, security.DefaultApiKeyValidatorName},
+		Match:      security.MatchAll,
+	})
+	security.WithPolicy(security.Policy{
+		Path:       "/keys",
+		Validators: []string{security.DefaultApiKeyValidatorName},
+	})
+	security.Security()
+	security.PermitRoute("/login", httpmethod.MethodPost)
+
+	engine := gin.New()
+	engine.POST("/login", func(c *gin.Context) {})
+	engine.GET("/users", func(c *gin.Context) {})
+	engine.GET("/reports", func(c *gin.Context) {})
+	engine.GET("/keys", func(c *gin.Context) {})
+
+	doc := Generate(engine.Routes(), Config{})
+	assert.Equal(t, map[string]SecurityScheme{
+		"jwt":            {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		"api_key_header": {Type: "apiKey", In: "header", Name: "X-Api-Key"},
+		"api_key_query":  {Type: "apiKey", In: "query", Name: "api_key"},
+	}, doc.Components.SecuritySchemes)
+	assert.Len(t, doc.Security, 3)
+	assert.Equal(t, &[]map[string][]string{}, doc.Paths["/login"]["post"].Security)
+	assert.Equal(t, &[]map[string][]string{
+		{"jwt": {}}, {"api_key_header": {}}, {"api_key_query": {}},
+	}, doc.Paths["/users"]["get"].Security)
+	assert.Equal(t, &[]map[string][]string{
+		{"jwt": {}, "api_key_header": {}}, {"jwt": {}, "api_key_query": {}},
+	}, doc.Paths["/reports"]["get"].Security)
+	assert.Equal(t, &[]map[string][]string{
+		{"api_key_header": {}}, {"api_key_query": {}},
+	}, doc.Paths["/keys"]["get"].Security)
+}
@@ -0,0 +1,252 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// generator converts Go types to schemas, registering named structs as components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema references named structs as components and inlines anonymous and generic ones,
+// so envelopes like core.Response[T] are described by their fields
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" || strings.Contains(t.Name(), "[") {
+		return g.objectSchema(t, jsonName)
+	}
+
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	// reserve the name before walking the fields of recursive types
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.objectSchema(t, jsonName)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// objectSchema describes the fields of a struct whose names are given by nameOf, flattening embedded structs
+func (g *generator) objectSchema(t reflect.Type, nameOf func(f reflect.StructField) (string, bool)) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields(t) {
+		name, ok := nameOf(f)
+		if !ok {
+			continue
+		}
+
+		property := g.schemaOf(f.Type)
+		if applyRules(property, f) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// fields returns the exported fields of a struct, replacing embedded structs without a json name by their fields
+func fields(t reflect.Type) []reflect.StructField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var result []reflect.StructField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if name, _ := tagName(f, "json"); name == "" && ft.Kind() == reflect.Struct {
+				result = append(result, fields(ft)...)
+				continue
+			}
+		}
+		if f.IsExported() {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func tagName(f reflect.StructField, tag string) (string, bool) {
+	value, ok := f.Tag.Lookup(tag)
+	name, _, _ := strings.Cut(value, ",")
+	return name, ok
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	name, _ := tagName(f, "json")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// applyRules maps the validation rules of the binding tag to schema constraints and reports whether the field is required
+func applyRules(schema *Schema, f reflect.StructField) bool {
+	rules := f.Tag.Get("binding")
+	if rules == "" {
+		rules = f.Tag.Get("validate")
+	}
+
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		key, param, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			// the remaining rules apply to the elements
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, enumValue(schema, value))
+			}
+		case "min", "gte":
+			setBound(schema, param, true)
+		case "max", "lte":
+			setBound(schema, param, false)
+		case "len":
+			setBound(schema, param, true)
+			setBound(schema, param, false)
+		}
+	}
+	if defaultValue := formDefault(f); defaultValue != "" {
+		schema.Default = enumValue(schema, defaultValue)
+	}
+	return required
+}
+
+func setBound(schema *Schema, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	case "string":
+		length := int(n)
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case "array":
+		length := int(n)
+		if lower {
+			schema.MinItems = &length
+		} else {
+			schema.MaxItems = &length
+		}
+	}
+}
+
+func enumValue(schema *Schema, value string) any {
+	switch schema.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// formDefault returns the default option of the form tag, e.g. form:"current,default=1"
+func formDefault(f reflect.StructField) string {
+	for _, option := range strings.Split(f.Tag.Get("form"), ",")[1:] {
+		if value, found := strings.CutPrefix(option, "default="); found {
+			return value
+		}
+	}
+	return ""
+}
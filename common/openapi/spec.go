@@ -0,0 +1,91 @@
+package openapi
+
+// Version of the OpenAPI specification generated
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi" yaml:"openapi"`
+	Info       Info                            `json:"info" yaml:"info"`
+	Servers    []Server                        `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths" yaml:"paths"`
+	Components Components                      `json:"components" yaml:"components"`
+	Security   []map[string][]string           `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url" yaml:"url"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type" yaml:"type"`
+	Scheme       string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty" yaml:"in,omitempty"`
+	Name         string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                 `json:"operationId" yaml:"operationId"`
+	Summary     string                 `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Deprecated  bool                   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Parameters  []Parameter            `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]Response    `json:"responses" yaml:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]MediaType `json:"content" yaml:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description" yaml:"description"`
+	Content     map[string]MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+// Schema is the subset of JSON Schema used to describe Go types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Default              any                `json:"default,omitempty" yaml:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+}
@@ -1,8 +1,10 @@
 package cronx
 
 import (
+	"context"
 	"strings"
 
+	"github.com/gfa-inc/gfa"
 	"github.com/gfa-inc/gfa/common/logger"
 	"github.com/robfig/cron/v3"
 )
@@ -42,3 +44,40 @@ func Setup() {
 			cron.SecondOptional|cron.Minute|cron.Hour|cron.Dom|cron.Month|cron.Dow|cron.Descriptor)))
 	C.Start()
 }
+
+// Component wraps Setup as a lifecycle component, waiting for running jobs on stop
+func Component() gfa.Component {
+	return &component{}
+}
+
+type component struct{}
+
+func (*component) Name() string {
+	return "cron"
+}
+
+func (*component) DependsOn() []string {
+	return nil
+}
+
+func (*component) Start(_ context.Context) error {
+	Setup()
+	return nil
+}
+
+func (*component) Stop(ctx context.Context) error {
+	if C == nil {
+		return nil
+	}
+
+	select {
+	case <-C.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (*component) Health(_ context.Context) error {
+	return nil
+}
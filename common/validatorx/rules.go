@@ -0,0 +1,190 @@
+package validatorx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/go-playground/validator/v10"
+	"github.com/samber/lo"
+)
+
+const (
+	TagMobile      = "mobile"
+	TagIDCard      = "idcard"
+	TagPassword    = "password"
+	TagEnum        = "enum"
+	TagDateTimeGte = "datetime_gte"
+
+	// DefaultPasswordLength is the minimal length of the password tag without param
+	DefaultPasswordLength = 8
+)
+
+var (
+	mobileRegex = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	idCardRegex = regexp.MustCompile(`^\d{17}[\dXx]$`)
+
+	idCardWeights   = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	idCardCheckCode = "10X98765432"
+
+	dateTimeLayouts = []string{time.DateTime, time.DateOnly, time.RFC3339}
+
+	enumMu sync.RWMutex
+	enums  = make(map[string][]string)
+
+	// paramNames keeps the json names of the fields named by the params of tags like datetime_gte, keyed by paramKey
+	paramNames sync.Map
+)
+
+// RegisterEnum declares the values allowed by the enum tag, e.g. binding:"enum=order_status"
+func RegisterEnum(name string, values ...any) {
+	enumMu.Lock()
+	defer enumMu.Unlock()
+	enums[name] = lo.Map(values, func(v any, _ int) string {
+		return fmt.Sprint(v)
+	})
+}
+
+func registerBuiltinRules() {
+	RegisterValidation(TagMobile, isMobile, map[string]string{
+		"zh": "{0}必须是有效的手机号码",
+		"en": "{0} must be a valid mobile number",
+	})
+	RegisterValidation(TagIDCard, isIDCard, map[string]string{
+		"zh": "{0}必须是有效的身份证号码",
+		"en": "{0} must be a valid ID card number",
+	})
+	RegisterValidation(TagPassword, isStrongPassword, map[string]string{
+		"zh": "{0}必须包含大小写字母、数字和特殊字符",
+		"en": "{0} must contain upper and lower case letters, digits and special characters",
+	})
+	RegisterValidation(TagEnum, isEnum, map[string]string{
+		"zh": "{0}不是有效的{1}",
+		"en": "{0} is not a valid {1}",
+	})
+	RegisterValidation(TagDateTimeGte, isDateTimeGte, map[string]string{
+		"zh": "{0}不能早于{1}",
+		"en": "{0} must not be earlier than {1}",
+	})
+}
+
+func paramKey(tag, structField, param string) string {
+	return tag + ":" + structField + "." + param
+}
+
+// rememberParamName keeps the json name of the field named by the param of a failed validation
+func rememberParamName(fl validator.FieldLevel) {
+	parent := fl.Parent()
+	for parent.Kind() == reflect.Pointer {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return
+	}
+	if f, ok := parent.Type().FieldByName(fl.Param()); ok {
+		if name := fieldName(f); name != "" {
+			paramNames.Store(paramKey(fl.GetTag(), fl.StructFieldName(), fl.Param()), name)
+		}
+	}
+}
+
+// isMobile validates mainland China mobile numbers
+func isMobile(fl validator.FieldLevel) bool {
+	return mobileRegex.MatchString(fl.Field().String())
+}
+
+// isIDCard validates 18 digit resident ID card numbers with their check code
+func isIDCard(fl validator.FieldLevel) bool {
+	id := fl.Field().String()
+	if !idCardRegex.MatchString(id) {
+		return false
+	}
+
+	sum := 0
+	for i, weight := range idCardWeights {
+		sum += int(id[i]-'0') * weight
+	}
+	return unicode.ToUpper(rune(id[17])) == rune(idCardCheckCode[sum%11])
+}
+
+// isStrongPassword requires upper and lower case letters, digits and special characters,
+// the param sets the minimal length which defaults to DefaultPasswordLength
+func isStrongPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+
+	minLength := DefaultPasswordLength
+	if fl.Param() != "" {
+		if n, err := strconv.Atoi(fl.Param()); err == nil {
+			minLength = n
+		}
+	}
+	if len([]rune(password)) < minLength {
+		return false
+	}
+
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			special = true
+		}
+	}
+	return upper && lower && digit && special
+}
+
+func isEnum(fl validator.FieldLevel) bool {
+	enumMu.RLock()
+	values, ok := enums[fl.Param()]
+	enumMu.RUnlock()
+	if !ok {
+		logger.Errorf("Validator enum %s not registered, rejecting field %s", fl.Param(), fl.StructFieldName())
+		return false
+	}
+	return lo.Contains(values, fmt.Sprint(fl.Field().Interface()))
+}
+
+// isDateTimeGte checks a datetime string is not earlier than the field named by the param.
+// Empty or malformed values pass, leaving them to required and datetime tags.
+func isDateTimeGte(fl validator.FieldLevel) bool {
+	other, kind, _, found := fl.GetStructFieldOKAdvanced2(fl.Parent(), fl.Param())
+	if !found || kind != reflect.String || fl.Field().Kind() != reflect.String {
+		return false
+	}
+
+	end, ok := parseDateTime(fl.Field().String())
+	if !ok {
+		return true
+	}
+	start, ok := parseDateTime(other.String())
+	if !ok {
+		return true
+	}
+	if end.Before(start) {
+		rememberParamName(fl)
+		return false
+	}
+	return true
+}
+
+func parseDateTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -3,44 +3,168 @@ package validatorx
 import (
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/gfa-inc/gfa/common/logger"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/zh"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	entrans "github.com/go-playground/validator/v10/translations/en"
 	zhtrans "github.com/go-playground/validator/v10/translations/zh"
 )
 
+// DefaultLocale is the locale of Trans
+var DefaultLocale = "zh"
+
 var (
 	uni   *ut.UniversalTranslator
 	Trans ut.Translator
+
+	mu       sync.RWMutex
+	validate *validator.Validate
 )
 
+// RegisterTranslationsFunc registers the default tag translations of a locale, e.g. zh.RegisterDefaultTranslations
+type RegisterTranslationsFunc func(v *validator.Validate, trans ut.Translator) error
+
 func Setup() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		enLoc := en.New()
-		zhLoc := zh.New()
-		uni = ut.New(enLoc, zhLoc)
-		Trans, _ = uni.GetTranslator("zh")
-		err := zhtrans.RegisterDefaultTranslations(v, Trans)
+		mu.Lock()
+		validate = v
+		uni = ut.New(en.New())
+		mu.Unlock()
+
+		RegisterLocale(en.New(), entrans.RegisterDefaultTranslations)
+		RegisterLocale(zh.New(), zhtrans.RegisterDefaultTranslations)
+		Trans = GetTranslator(DefaultLocale)
+		registerBuiltinRules()
+
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName is the name of a field in the messages and paths of the errors, its json or form name
+func fieldName(fld reflect.StructField) string {
+	tagValue := fld.Tag.Get("json")
+	if tagValue == "" {
+		tagValue = fld.Tag.Get("form")
+	}
+	name := strings.SplitN(tagValue, ",", 2)[0]
+
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// RegisterLocale adds a locale and registers its default tag translations
+func RegisterLocale(locale locales.Translator, register RegisterTranslationsFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	if uni == nil {
+		logger.Panic("Validator is not set up")
+	}
+
+	err := uni.AddTranslator(locale, true)
+	if err != nil {
+		logger.Panic(err)
+	}
+	trans, _ := uni.GetTranslator(locale.Locale())
+	if register != nil {
+		if err = register(validate, trans); err != nil {
+			logger.Panic(err)
+		}
+	}
+	logger.Debugf("Validator locale %s registered", locale.Locale())
+}
+
+// RegisterValidation registers a custom tag together with its messages per locale, see RegisterTranslation
+func RegisterValidation(tag string, fn validator.Func, messages map[string]string, callValidationEvenIfNull ...bool) {
+	mu.RLock()
+	v := validate
+	mu.RUnlock()
+	if v == nil {
+		logger.Panic("Validator is not set up")
+	}
+
+	err := v.RegisterValidation(tag, fn, callValidationEvenIfNull...)
+	if err != nil {
+		logger.Panic(err)
+	}
+	RegisterTranslation(tag, messages)
+	logger.Debugf("Validator tag %s registered", tag)
+}
+
+// RegisterTranslation sets the message of a tag per locale, {0} is replaced by the field name and {1} by the tag param
+func RegisterTranslation(tag string, messages map[string]string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if uni == nil {
+		logger.Panic("Validator is not set up")
+	}
+
+	for locale, message := range messages {
+		trans, found := uni.GetTranslator(locale)
+		if !found {
+			logger.Panicf("Validator locale %s not registered", locale)
+		}
+
+		err := validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+			return ut.Add(tag, message, true)
+		}, translate)
 		if err != nil {
 			logger.Panic(err)
 		}
+	}
+}
 
-		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
-			tagValue := fld.Tag.Get("json")
-			if tagValue == "" {
-				tagValue = fld.Tag.Get("form")
-			}
-			name := strings.SplitN(tagValue, ",", 2)[0]
+func translate(ut ut.Translator, fe validator.FieldError) string {
+	// params naming a field are shown by its json name like the field of the error
+	param := fe.Param()
+	if name, ok := paramNames.Load(paramKey(fe.Tag(), fe.StructField(), param)); ok {
+		param = name.(string)
+	}
+	message, err := ut.T(fe.Tag(), fe.Field(), param)
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
+
+// MatchLocale returns the registered locale matching a language tag like zh-CN, trying its base language too
+func MatchLocale(tag string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if uni == nil || tag == "" {
+		return "", false
+	}
 
-			if name == "-" {
-				return ""
-			}
+	locale := strings.ReplaceAll(tag, "-", "_")
+	for {
+		if _, found := uni.GetTranslator(locale); found {
+			return locale, true
+		}
+		i := strings.LastIndex(locale, "_")
+		if i < 0 {
+			return "", false
+		}
+		locale = locale[:i]
+	}
+}
 
-			return name
-		})
+// GetTranslator returns the translator of a language tag, falling back to Trans
+func GetTranslator(tag string) ut.Translator {
+	locale, ok := MatchLocale(tag)
+	if !ok {
+		return Trans
 	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	trans, _ := uni.GetTranslator(locale)
+	return trans
 }
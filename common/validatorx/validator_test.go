@@ -0,0 +1,102 @@
+package validatorx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+type account struct {
+	Mobile   string `json:"mobile" binding:"omitempty,mobile"`
+	IDCard   string `json:"id_card" binding:"omitempty,idcard"`
+	Password string `json:"password" binding:"omitempty,password"`
+	Status   string `json:"status" binding:"omitempty,enum=account_status"`
+	Level    int    `json:"level" binding:"omitempty,enum=account_level"`
+	From     string `json:"from"`
+	To       string `json:"to" binding:"omitempty,datetime_gte=From"`
+}
+
+type product struct {
+	SKU string `json:"sku" binding:"sku"`
+}
+
+func validationErrs(t *testing.T, obj any) validator.ValidationErrors {
+	err := binding.Validator.ValidateStruct(obj)
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	assert.True(t, errors.As(err, &errs))
+	return errs
+}
+
+func TestBuiltinRules(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	Setup()
+	RegisterEnum("account_status", "active", "locked")
+	RegisterEnum("account_level", 1, 2, 3)
+
+	valid := account{
+		Mobile:   "13812345678",
+		IDCard:   "11010519491231002X",
+		Password: "Passw0rd!",
+		Status:   "active",
+		Level:    2,
+		From:     "2024-01-01 00:00:00",
+		To:       "2024-01-02 00:00:00",
+	}
+	assert.Nil(t, validationErrs(t, &valid))
+
+	invalid := account{
+		Mobile:   "12812345678",
+		IDCard:   "110105194912310021",
+		Password: "password",
+		Status:   "deleted",
+		Level:    4,
+		From:     "2024-01-02 00:00:00",
+		To:       "2024-01-01 00:00:00",
+	}
+	errs := validationErrs(t, &invalid)
+	tags := make([]string, 0, len(errs))
+	for _, err := range errs {
+		tags = append(tags, err.Tag())
+	}
+	assert.Equal(t, []string{TagMobile, TagIDCard, TagPassword, TagEnum, TagEnum, TagDateTimeGte}, tags)
+	assert.Equal(t, "mobile必须是有效的手机号码", errs[0].Translate(Trans))
+	assert.Equal(t, "to must not be earlier than from", errs[5].Translate(GetTranslator("en-US")))
+	assert.Equal(t, "to不能早于from", errs[5].Translate(Trans))
+
+	// unregistered enums fail the validation instead of panicking
+	errs = validationErrs(t, &struct {
+		Kind string `json:"kind" binding:"enum=missing"`
+	}{Kind: "a"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, TagEnum, errs[0].Tag())
+}
+
+func TestRegisterValidation(t *testing.T) {
+	Setup()
+	RegisterValidation("sku", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String()) == 8
+	}, map[string]string{
+		"zh": "{0}不是有效的 SKU",
+		"en": "{0} must be a valid SKU",
+	})
+
+	errs := validationErrs(t, &product{SKU: "abc"})
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "sku不是有效的 SKU", errs[0].Translate(GetTranslator("zh-CN")))
+	assert.Equal(t, "sku must be a valid SKU", errs[0].Translate(GetTranslator("en")))
+
+	locale, ok := MatchLocale("zh-Hans-CN")
+	assert.True(t, ok)
+	assert.Equal(t, "zh", locale)
+	_, ok = MatchLocale("fr")
+	assert.False(t, ok)
+}
@@ -0,0 +1,251 @@
+package gfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gfa-inc/gfa/common/aws"
+	"github.com/gfa-inc/gfa/common/cache"
+	"github.com/gfa-inc/gfa/common/db"
+	"github.com/gfa-inc/gfa/common/db/mysqlx/migrate"
+	"github.com/gfa-inc/gfa/common/health"
+	"github.com/gfa-inc/gfa/common/httpx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/messenger/mailx"
+	"github.com/gfa-inc/gfa/common/mq"
+	"github.com/gfa-inc/gfa/common/nsdb"
+	"github.com/gfa-inc/gfa/common/validatorx"
+	"github.com/samber/lo"
+)
+
+// Names of the built-in components, usable in DependsOn
+const (
+	ComponentCache     = "cache"
+	ComponentDB        = "db"
+	ComponentMigrate   = "migrate"
+	ComponentNSDB      = "nsdb"
+	ComponentMQ        = "mq"
+	ComponentValidator = "validator"
+	ComponentAWS       = "aws"
+	ComponentMail      = "mail"
+	ComponentHTTP      = "http_client"
+)
+
+var (
+	ErrComponentCycle = errors.New("component dependency cycle detected")
+)
+
+// Component is a unit with a managed lifecycle. Components are started by Default
+// in dependency order and stopped in reverse order when Run shuts down.
+type Component interface {
+	Name() string
+	DependsOn() []string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Health(ctx context.Context) error
+}
+
+// ComponentWrapper adapts plain functions to the Component interface
+type ComponentWrapper struct {
+	name      string
+	dependsOn []string
+	start     func(ctx context.Context) error
+	stop      func(ctx context.Context) error
+	health    func(ctx context.Context) error
+}
+
+func (cw *ComponentWrapper) Name() string {
+	return cw.name
+}
+
+func (cw *ComponentWrapper) DependsOn() []string {
+	return cw.dependsOn
+}
+
+func (cw *ComponentWrapper) Start(ctx context.Context) error {
+	if cw.start == nil {
+		return nil
+	}
+	return cw.start(ctx)
+}
+
+func (cw *ComponentWrapper) Stop(ctx context.Context) error {
+	if cw.stop == nil {
+		return nil
+	}
+	return cw.stop(ctx)
+}
+
+func (cw *ComponentWrapper) Health(ctx context.Context) error {
+	if cw.health == nil {
+		return nil
+	}
+	return cw.health(ctx)
+}
+
+// WithStop sets the function called on shutdown
+func (cw *ComponentWrapper) WithStop(stop func(ctx context.Context) error) *ComponentWrapper {
+	cw.stop = stop
+	return cw
+}
+
+// WithHealth sets the function reporting the component health
+func (cw *ComponentWrapper) WithHealth(health func(ctx context.Context) error) *ComponentWrapper {
+	cw.health = health
+	return cw
+}
+
+// NewComponent creates a component from a start function and the names of the components it depends on
+func NewComponent(name string, start func(ctx context.Context) error, dependsOn ...string) *ComponentWrapper {
+	return &ComponentWrapper{
+		name:      name,
+		dependsOn: dependsOn,
+		start:     start,
+	}
+}
+
+func builtinComponents() []Component {
+	return []Component{
+		NewComponent(ComponentCache, func(ctx context.Context) error {
+			cache.Setup()
+			return nil
+		}).WithStop(func(ctx context.Context) error {
+			return cache.Close()
+		}).WithHealth(cache.Ping),
+		NewComponent(ComponentDB, func(ctx context.Context) error {
+			db.Setup()
+			return nil
+		}).WithStop(func(ctx context.Context) error {
+			return db.Close()
+		}).WithHealth(db.Ping),
+		NewComponent(ComponentMigrate, migrate.Setup, ComponentDB),
+		NewComponent(ComponentNSDB, func(ctx context.Context) error {
+			nsdb.Setup()
+			return nil
+		}).WithStop(nsdb.Close).WithHealth(nsdb.Ping),
+		NewComponent(ComponentMQ, func(ctx context.Context) error {
+			mq.Setup()
+			return nil
+		}).WithStop(func(ctx context.Context) error {
+			return mq.Close()
+		}).WithHealth(mq.Ping),
+		NewComponent(ComponentValidator, func(ctx context.Context) error {
+			validatorx.Setup()
+			return nil
+		}),
+		NewComponent(ComponentAWS, func(ctx context.Context) error {
+			aws.Setup()
+			return nil
+		}).WithHealth(aws.Ping),
+		NewComponent(ComponentMail, func(ctx context.Context) error {
+			mailx.Setup()
+			return nil
+		}).WithStop(func(ctx context.Context) error {
+			return mailx.Close()
+		}).WithHealth(mailx.Ping),
+		NewComponent(ComponentHTTP, func(ctx context.Context) error {
+			httpx.Setup()
+			return nil
+		}).WithStop(func(ctx context.Context) error {
+			httpx.Close()
+			return nil
+		}),
+	}
+}
+
+// sortComponents orders components so that every component comes after its dependencies,
+// keeping the registration order between independent components
+func sortComponents(components []Component) ([]Component, error) {
+	registered := make(map[string]struct{}, len(components))
+	for _, c := range components {
+		if _, ok := registered[c.Name()]; ok {
+			return nil, fmt.Errorf("component %s registered twice", c.Name())
+		}
+		registered[c.Name()] = struct{}{}
+	}
+	for _, c := range components {
+		for _, dep := range c.DependsOn() {
+			if _, ok := registered[dep]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", c.Name(), dep)
+			}
+		}
+	}
+
+	sorted := make([]Component, 0, len(components))
+	placed := make(map[string]struct{}, len(components))
+	pending := components
+	for len(pending) > 0 {
+		var rest []Component
+		for _, c := range pending {
+			ready := lo.EveryBy(c.DependsOn(), func(dep string) bool {
+				_, ok := placed[dep]
+				return ok
+			})
+			if ready {
+				sorted = append(sorted, c)
+				placed[c.Name()] = struct{}{}
+			} else {
+				rest = append(rest, c)
+			}
+		}
+
+		if len(rest) == len(pending) {
+			names := lo.Map(rest, func(c Component, _ int) string {
+				return c.Name()
+			})
+			return nil, fmt.Errorf("%w: %s", ErrComponentCycle, strings.Join(names, ", "))
+		}
+		pending = rest
+	}
+
+	return sorted, nil
+}
+
+func (g *Gfa) WithComponent(components ...Component) {
+	g.components = append(g.components, components...)
+}
+
+// startComponents starts the built-in and registered components in dependency order,
+// registering the health check of each started component
+func (g *Gfa) startComponents(ctx context.Context) {
+	sorted, err := sortComponents(append(builtinComponents(), g.components...))
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	for _, c := range sorted {
+		logger.Debugf("Starting component %s", c.Name())
+		if err = c.Start(ctx); err != nil {
+			logger.Panicf("Fail to start component %s, %s", c.Name(), err)
+		}
+		g.started = append(g.started, c)
+
+		if cw, ok := c.(*ComponentWrapper); !ok || cw.health != nil {
+			health.Register(c.Name(), c.Health)
+		}
+	}
+
+	logger.Infof("%d components have been started: %s", len(g.started),
+		strings.Join(lo.Map(g.started, func(c Component, _ int) string {
+			return c.Name()
+		}), ", "))
+}
+
+// stopComponents stops the started components in reverse order
+func (g *Gfa) stopComponents(ctx context.Context) {
+	for i := len(g.started) - 1; i >= 0; i-- {
+		c := g.started[i]
+		logger.Debugf("Stopping component %s", c.Name())
+		if err := c.Stop(ctx); err != nil {
+			logger.Errorf("Fail to stop component %s, %s", c.Name(), err)
+		}
+	}
+	g.started = nil
+}
+
+// WithComponent registers components started by Default and stopped when Run shuts down
+func WithComponent(components ...Component) {
+	gfa.WithComponent(components...)
+}
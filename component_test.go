@@ -0,0 +1,77 @@
+package gfa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func componentNames(components []Component) []string {
+	return lo.Map(components, func(c Component, _ int) string {
+		return c.Name()
+	})
+}
+
+func TestSortComponents(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	t.Run("dependencies first, registration order kept", func(t *testing.T) {
+		sorted, err := sortComponents([]Component{
+			NewComponent("casbin", noop, "db"),
+			NewComponent("cron", noop),
+			NewComponent("db", noop),
+			NewComponent("cache", noop),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"cron", "db", "cache", "casbin"}, componentNames(sorted))
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		_, err := sortComponents([]Component{
+			NewComponent("casbin", noop, "db"),
+		})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("duplicated name", func(t *testing.T) {
+		_, err := sortComponents([]Component{
+			NewComponent("db", noop),
+			NewComponent("db", noop),
+		})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := sortComponents([]Component{
+			NewComponent("a", noop, "b"),
+			NewComponent("b", noop, "a"),
+			NewComponent("c", noop),
+		})
+		assert.ErrorIs(t, err, ErrComponentCycle)
+	})
+}
+
+func TestStopComponents(t *testing.T) {
+	logger.Setup()
+
+	var stopped []string
+	stop := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		}
+	}
+
+	g := NewGfa()
+	g.started = []Component{
+		NewComponent("db", nil).WithStop(stop("db")),
+		NewComponent("casbin", nil, "db").WithStop(stop("casbin")),
+	}
+	g.stopComponents(context.Background())
+
+	assert.Equal(t, []string{"casbin", "db"}, stopped)
+	assert.Empty(t, g.started)
+}
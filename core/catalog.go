@@ -0,0 +1,132 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gfa-inc/gfa/utils/lang"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLocale is used when no message matches the locale of the request
+var DefaultLocale = "zh"
+
+// ErrDef declares a business error once: its stable code, the HTTP status it is rendered with,
+// the level it is logged at and its message templates per locale
+type ErrDef struct {
+	Code   string `json:"code"`
+	Status int    `json:"status"`
+	// Level is one of debug, info, warn and error, defaults to error for 5xx statuses and warn otherwise
+	Level string `json:"level"`
+	// Messages are fmt templates keyed by locale, e.g. "en" or "zh-TW"
+	Messages map[string]string `json:"messages"`
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = make(map[string]ErrDef)
+)
+
+// DefineErr registers an error definition and returns the BizErr carrying its code.
+// It panics if the code is already defined, so declare errors as package level variables.
+func DefineErr(def ErrDef) *BizErr {
+	if def.Code == "" {
+		panic("core: error code must not be empty")
+	}
+	if def.Status == 0 {
+		def.Status = http.StatusOK
+	}
+	if def.Level == "" {
+		def.Level = "warn"
+		if def.Status >= http.StatusInternalServerError {
+			def.Level = "error"
+		}
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if _, ok := catalog[def.Code]; ok {
+		panic(fmt.Sprintf("core: error code %s already defined", def.Code))
+	}
+	catalog[def.Code] = def
+
+	return &BizErr{
+		Code:    def.Code,
+		Message: def.Message(DefaultLocale),
+	}
+}
+
+// LookupErr returns the definition of an error code
+func LookupErr(code string) (ErrDef, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	def, ok := catalog[code]
+	return def, ok
+}
+
+// ErrCatalog returns every defined error sorted by code
+func ErrCatalog() []ErrDef {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	defs := make([]ErrDef, 0, len(catalog))
+	for _, def := range catalog {
+		defs = append(defs, def)
+	}
+	slices.SortFunc(defs, func(a, b ErrDef) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return defs
+}
+
+// ExportErrCatalog writes the catalog as a JSON array, e.g. for generating frontend error tables
+func ExportErrCatalog(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ErrCatalog())
+}
+
+// Message formats the template of the locale, falling back to its base language and then to DefaultLocale
+func (d ErrDef) Message(locale string, args ...any) string {
+	tmpl, ok := d.template(locale)
+	if !ok {
+		tmpl, ok = d.template(DefaultLocale)
+	}
+	if !ok {
+		return ""
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
+
+func (d ErrDef) template(locale string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+	if tmpl, ok := d.Messages[locale]; ok {
+		return tmpl, true
+	}
+	base, _, found := strings.Cut(locale, "-")
+	if !found {
+		return "", false
+	}
+	tmpl, ok := d.Messages[base]
+	return tmpl, ok
+}
+
+// Locale returns the locale chosen by the locale middleware, or the preferred one of the Accept-Language header
+func Locale(c *gin.Context) string {
+	if tag := lang.Get(c); tag != "" {
+		return tag
+	}
+	if tags := lang.ParseAcceptLanguage(c.GetHeader("Accept-Language")); len(tags) > 0 {
+		return tags[0]
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gfa-inc/gfa/utils/lang"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefineErr(t *testing.T) {
+	errNotFound := DefineErr(ErrDef{
+		Code:   "USER_NOT_FOUND",
+		Status: http.StatusNotFound,
+		Messages: map[string]string{
+			"zh": "用户 %s 不存在",
+			"en": "user %s not found",
+		},
+	})
+
+	def, ok := LookupErr("USER_NOT_FOUND")
+	assert.True(t, ok)
+	assert.Equal(t, "warn", def.Level)
+	assert.Equal(t, "user tom not found", def.Message("en-US", "tom"))
+	assert.Equal(t, "用户 tom 不存在", def.Message("fr", "tom"))
+
+	err := fmt.Errorf("query: %w", errNotFound.WithField("tom"))
+	assert.True(t, errors.Is(err, errNotFound))
+	var bizErr *BizErr
+	assert.True(t, errors.As(err, &bizErr))
+	assert.Equal(t, []any{"tom"}, bizErr.Args)
+
+	assert.Panics(t, func() {
+		DefineErr(ErrDef{Code: "USER_NOT_FOUND"})
+	})
+}
+
+func TestExportErrCatalog(t *testing.T) {
+	DefineErr(ErrDef{Code: "B_EXPORT", Status: http.StatusServiceUnavailable, Messages: map[string]string{"zh": "b"}})
+	DefineErr(ErrDef{Code: "A_EXPORT", Messages: map[string]string{"zh": "a"}})
+
+	var buf bytes.Buffer
+	assert.Nil(t, ExportErrCatalog(&buf))
+
+	var defs []ErrDef
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &defs))
+	codes := make([]string, 0, len(defs))
+	for _, def := range defs {
+		codes = append(codes, def.Code)
+	}
+	assert.Subset(t, codes, []string{"A_EXPORT", "B_EXPORT"})
+	assert.Less(t, slices.Index(codes, "A_EXPORT"), slices.Index(codes, "B_EXPORT"))
+
+	def, _ := LookupErr("B_EXPORT")
+	assert.Equal(t, "error", def.Level)
+	def, _ = LookupErr("A_EXPORT")
+	assert.Equal(t, http.StatusOK, def.Status)
+}
+
+func TestLocale(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, DefaultLocale, Locale(c))
+
+	c.Request.Header.Set("Accept-Language", "zh;q=0.5, en-US")
+	assert.Equal(t, "en-US", Locale(c))
+
+	c.Set(lang.ContextKey, "ja")
+	assert.Equal(t, "ja", Locale(c))
+}
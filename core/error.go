@@ -1,14 +1,30 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/gfa-inc/gfa/common/validatorx"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
 
 type ParamErr struct {
 	Message string
+	// Fields lists every invalid field when the error comes from validation
+	Fields []FieldError
+
+	validationErrs validator.ValidationErrors
+}
+
+// FieldError describes a field failing validation
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. items[2].sku
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
 
 func (p *ParamErr) Error() string {
@@ -25,12 +41,11 @@ func NewParamErr(data any) *ParamErr {
 	switch msg := data.(type) {
 	case string:
 		message = msg
-	case validator.ValidationErrors:
-		for _, err := range msg {
-			message = err.Translate(validatorx.Trans)
-			break
-		}
 	case error:
+		var validationErrs validator.ValidationErrors
+		if errors.As(msg, &validationErrs) {
+			return newValidationErr(validationErrs)
+		}
 		message = msg.Error()
 	}
 	return &ParamErr{
@@ -38,9 +53,51 @@ func NewParamErr(data any) *ParamErr {
 	}
 }
 
+// Localize translates the field errors with the translator of the request, e.g. locale.Translator(c)
+func (p *ParamErr) Localize(trans ut.Translator) *ParamErr {
+	if len(p.validationErrs) == 0 {
+		return p
+	}
+	return translateValidationErrs(p.validationErrs, trans)
+}
+
+func newValidationErr(errs validator.ValidationErrors) *ParamErr {
+	return translateValidationErrs(errs, validatorx.Trans)
+}
+
+func translateValidationErrs(errs validator.ValidationErrors, trans ut.Translator) *ParamErr {
+	fields := make([]FieldError, 0, len(errs))
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		message := err.Translate(trans)
+		fields = append(fields, FieldError{
+			Field:   fieldPath(err.Namespace()),
+			Tag:     err.Tag(),
+			Param:   err.Param(),
+			Message: message,
+		})
+		messages = append(messages, message)
+	}
+	return &ParamErr{
+		Message:        strings.Join(messages, "; "),
+		Fields:         fields,
+		validationErrs: errs,
+	}
+}
+
+// fieldPath drops the top level struct name from the namespace of a validation error
+func fieldPath(namespace string) string {
+	if _, path, found := strings.Cut(namespace, "."); found {
+		return path
+	}
+	return namespace
+}
+
 type BizErr struct {
 	Code    string
 	Message string
+	// Args are kept to format the localized message of the catalog definition
+	Args []any
 }
 
 func (b *BizErr) Error() string {
@@ -49,9 +106,16 @@ func (b *BizErr) Error() string {
 
 func (b BizErr) WithField(vals ...any) *BizErr {
 	b.Message = fmt.Sprintf(b.Message, vals...)
+	b.Args = vals
 	return &b
 }
 
+// Is matches business errors by code, so errors derived with WithField still match their definition
+func (b *BizErr) Is(target error) bool {
+	t, ok := target.(*BizErr)
+	return ok && t.Code != "" && t.Code == b.Code
+}
+
 func NewBizErr(code, message string) *BizErr {
 	return &BizErr{
 		Code:    code,
@@ -74,12 +138,27 @@ func NewAuthErr(message string) *AuthErr {
 }
 
 type UnauthorizedErr struct {
+	// Err is the reason of the failed authentication, e.g. an expired token
+	Err error
 }
 
 func (u *UnauthorizedErr) Error() string {
+	if u.Err != nil {
+		return u.Err.Error()
+	}
 	return "Unauthorized"
 }
 
+func (u *UnauthorizedErr) Unwrap() error {
+	return u.Err
+}
+
+// WithErr returns a copy with the reason of the failed authentication
+func (u UnauthorizedErr) WithErr(err error) *UnauthorizedErr {
+	u.Err = err
+	return &u
+}
+
 func NewUnauthorizedErr() *UnauthorizedErr {
 	return &UnauthorizedErr{}
 }
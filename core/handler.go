@@ -0,0 +1,273 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// HandlerMeta keeps the types of a handler built by Handle, e.g. for generating API documents
+type HandlerMeta struct {
+	Request     reflect.Type
+	Response    reflect.Type
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+}
+
+type HandlerOption func(meta *HandlerMeta)
+
+// handlerMetas keeps the metadata of the handlers built by Handle keyed by handlerKey
+var handlerMetas sync.Map
+
+type describedHandler struct {
+	// handler stays referenced so its key is not reused by another function
+	handler gin.HandlerFunc
+	meta    HandlerMeta
+}
+
+// handlerKey identifies a function value, closures sharing their code have distinct keys
+func handlerKey(h gin.HandlerFunc) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&h))
+}
+
+func WithSummary(summary string) HandlerOption {
+	return func(meta *HandlerMeta) {
+		meta.Summary = summary
+	}
+}
+
+func WithDescription(description string) HandlerOption {
+	return func(meta *HandlerMeta) {
+		meta.Description = description
+	}
+}
+
+func WithTags(tags ...string) HandlerOption {
+	return func(meta *HandlerMeta) {
+		meta.Tags = append(meta.Tags, tags...)
+	}
+}
+
+func Deprecated() HandlerOption {
+	return func(meta *HandlerMeta) {
+		meta.Deprecated = true
+	}
+}
+
+// Handle adapts a typed function to a gin handler. The request struct is bound from the path (uri tag),
+// headers (header tag), query (form tag) and body (json or form tag), then validated. Errors are passed
+// to OnError and the result is rendered by OK. The function receives a context reading the keys of the gin context
+// and the span, values, deadline and cancellation of the request context.
+func Handle[Req any, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...HandlerOption) gin.HandlerFunc {
+	meta := HandlerMeta{
+		Request:  reflect.TypeFor[Req](),
+		Response: reflect.TypeFor[Resp](),
+	}
+	for _, opt := range opts {
+		opt(&meta)
+	}
+	if meta.Request.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("core: request type %s of Handle must be a struct", meta.Request))
+	}
+
+	h := func(c *gin.Context) {
+		var req Req
+		if err := Bind(c, &req); err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		resp, err := fn(requestContext{c}, req)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		OK(c, resp)
+	}
+	handlerMetas.Store(handlerKey(h), describedHandler{handler: h, meta: meta})
+	return h
+}
+
+// requestContext falls back to the request context of the gin context whatever the ContextWithFallback
+// of the engine, so the spans and client disconnects of the request reach the layers below
+type requestContext struct {
+	*gin.Context
+}
+
+func (c requestContext) Deadline() (time.Time, bool) {
+	return c.Request.Context().Deadline()
+}
+
+func (c requestContext) Done() <-chan struct{} {
+	return c.Request.Context().Done()
+}
+
+func (c requestContext) Err() error {
+	return c.Request.Context().Err()
+}
+
+func (c requestContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.Request.Context().Value(key)
+}
+
+// DescribeHandler returns the metadata of a handler built by Handle
+func DescribeHandler(h gin.HandlerFunc) (HandlerMeta, bool) {
+	if h == nil {
+		return HandlerMeta{}, false
+	}
+	v, ok := handlerMetas.Load(handlerKey(h))
+	if !ok {
+		return HandlerMeta{}, false
+	}
+	return v.(describedHandler).meta, true
+}
+
+// CopyHandlerMeta gives a wrapper the metadata of the handler it wraps, so it is still documented
+func CopyHandlerMeta(wrapper, h gin.HandlerFunc) gin.HandlerFunc {
+	if meta, ok := DescribeHandler(h); ok {
+		handlerMetas.Store(handlerKey(wrapper), describedHandler{handler: wrapper, meta: meta})
+	}
+	return wrapper
+}
+
+// Bind fills obj from the path, headers, query and body of the request and validates it,
+// failures are returned as ParamErr
+func Bind(c *gin.Context, obj any) error {
+	params := make(map[string][]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = []string{param.Value}
+	}
+	if err := binding.MapFormWithTag(obj, params, "uri"); err != nil {
+		return NewParamErr(err)
+	}
+
+	headers := make(map[string][]string)
+	for _, name := range tagNames(reflect.TypeOf(obj), "header") {
+		if values := c.Request.Header.Values(name); len(values) > 0 {
+			headers[name] = values
+		}
+	}
+	if err := binding.MapFormWithTag(obj, headers, "header"); err != nil {
+		return NewParamErr(err)
+	}
+
+	form, err := formValues(c)
+	if err != nil {
+		return NewParamErr(err)
+	}
+	if err = binding.MapFormWithTag(obj, form, "form"); err != nil {
+		return NewParamErr(err)
+	}
+
+	if err := bindBody(c, obj); err != nil {
+		return NewParamErr(err)
+	}
+
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return NewParamErr(err)
+	}
+	return nil
+}
+
+func hasBody(c *gin.Context) bool {
+	return c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0
+}
+
+// formValues merges the query and the form body, whose values take precedence, so the defaults
+// of the form tags apply once to the keys missing from both
+func formValues(c *gin.Context) (url.Values, error) {
+	values := c.Request.URL.Query()
+	if !hasBody(c) {
+		return values, nil
+	}
+
+	var body map[string][]string
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return nil, err
+		}
+		body = c.Request.PostForm
+	case binding.MIMEMultipartPOSTForm:
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		body = c.Request.MultipartForm.Value
+	}
+	for key, value := range body {
+		values[key] = value
+	}
+	return values, nil
+}
+
+func bindBody(c *gin.Context, obj any) error {
+	if !hasBody(c) {
+		return nil
+	}
+
+	switch c.ContentType() {
+	case binding.MIMEJSON:
+		decoder := json.NewDecoder(c.Request.Body)
+		if binding.EnableDecoderUseNumber {
+			decoder.UseNumber()
+		}
+		if binding.EnableDecoderDisallowUnknownFields {
+			decoder.DisallowUnknownFields()
+		}
+		if err := decoder.Decode(obj); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		// bound with the query
+		return nil
+	default:
+		return fmt.Errorf("unsupported content type %s", c.ContentType())
+	}
+}
+
+// tagNames collects the names of a tag in a struct type, including its nested structs
+func tagNames(t reflect.Type, tag string) []string {
+	return collectTagNames(t, tag, make(map[reflect.Type]bool))
+}
+
+func collectTagNames(t reflect.Type, tag string, visited map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			names = append(names, name)
+			continue
+		}
+		names = append(names, collectTagNames(f.Type, tag, visited)...)
+	}
+	return names
+}
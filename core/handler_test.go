@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type updateUserReq struct {
+	ID      int    `uri:"id" binding:"required"`
+	Tenant  string `header:"X-Tenant-ID" binding:"required"`
+	DryRun  bool   `form:"dry_run"`
+	Version int    `form:"version,default=1"`
+	Name    string `json:"name" binding:"required"`
+}
+
+type userResp struct {
+	ID      int    `json:"id"`
+	Tenant  string `json:"tenant"`
+	Name    string `json:"name"`
+	DryRun  bool   `json:"dryRun"`
+	Version int    `json:"version"`
+}
+
+func updateUser(ctx context.Context, req updateUserReq) (userResp, error) {
+	if req.Name == "taken" {
+		return userResp{}, NewBizErr("NAME_TAKEN", "name is taken")
+	}
+	return userResp{req.ID, req.Tenant, req.Name, req.DryRun, req.Version}, nil
+}
+
+func TestHandle(t *testing.T) {
+	engine := gin.New()
+	engine.PUT("/users/:id", Handle(updateUser, WithSummary("Update user"), WithTags("user")))
+
+	serve := func(target string, tenant string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/users/7?dry_run=true", "acme", `{"name":"tom"}`)
+	var resp Response[userResp]
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, userResp{7, "acme", "tom", true, 1}, resp.Data)
+
+	// errors are left to OnError
+	var c *gin.Context
+	engine.Use(func(ctx *gin.Context) {
+		c = ctx
+	})
+	engine.PUT("/accounts/:id", Handle(updateUser))
+	w = serve("/accounts/7", "", `{"name":"tom"}`)
+	var paramErr *ParamErr
+	assert.ErrorAs(t, c.Errors.Last(), &paramErr)
+	assert.Equal(t, "Tenant", paramErr.Fields[0].Field)
+	assert.Equal(t, "required", paramErr.Fields[0].Tag)
+
+	w = serve("/accounts/7", "acme", `{"name":"taken"}`)
+	var bizErr *BizErr
+	assert.ErrorAs(t, c.Errors.Last(), &bizErr)
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestDescribeHandler(t *testing.T) {
+	meta, ok := DescribeHandler(Handle(updateUser, WithSummary("Update user"), WithTags("user")))
+	assert.True(t, ok)
+	assert.Equal(t, reflect.TypeFor[updateUserReq](), meta.Request)
+	assert.Equal(t, reflect.TypeFor[userResp](), meta.Response)
+	assert.Equal(t, "Update user", meta.Summary)
+	assert.Equal(t, []string{"user"}, meta.Tags)
+
+	// handlers of the same types are described apart
+	meta, ok = DescribeHandler(Handle(updateUser, WithSummary("Rename user")))
+	assert.True(t, ok)
+	assert.Equal(t, "Rename user", meta.Summary)
+
+	h := Handle(updateUser, WithSummary("Update user"))
+	wrapper := func(c *gin.Context) {
+		h(c)
+	}
+	_, ok = DescribeHandler(wrapper)
+	assert.False(t, ok)
+	meta, ok = DescribeHandler(CopyHandlerMeta(wrapper, h))
+	assert.True(t, ok)
+	assert.Equal(t, "Update user", meta.Summary)
+
+	_, ok = DescribeHandler(func(c *gin.Context) {
+		panic("must not be called")
+	})
+	assert.False(t, ok)
+}
+
+type ctxKey struct{}
+
+func TestHandleContext(t *testing.T) {
+	type req struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "span"))
+	cancel()
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("user", "tom")
+		c.Request = c.Request.WithContext(ctx)
+	})
+	engine.GET("/ctx", Handle(func(ctx context.Context, _ req) (string, error) {
+		return fmt.Sprintf("%v %v %v", ctx.Value(ctxKey{}), ctx.Value("user"), ctx.Err()), nil
+	}))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ctx", nil))
+	var resp Response[string]
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "span tom context canceled", resp.Data)
+}
+
+func TestBindForm(t *testing.T) {
+	type pageReq struct {
+		Page int    `form:"page,default=1"`
+		Size int    `form:"size,default=10"`
+		Name string `form:"name"`
+	}
+
+	bind := func(target, body string) pageReq {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		var req pageReq
+		assert.Nil(t, Bind(c, &req))
+		return req
+	}
+
+	// the query is not reset by the defaults of the keys missing from the body
+	assert.Equal(t, pageReq{Page: 3, Size: 10, Name: "tom"}, bind("/?page=3", "name=tom"))
+	assert.Equal(t, pageReq{Page: 5, Size: 20, Name: "tom"}, bind("/?page=3&size=20", "page=5&name=tom"))
+	assert.Equal(t, pageReq{Page: 1, Size: 10}, bind("/", "name="))
+}
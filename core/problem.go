@@ -0,0 +1,111 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type ResponseFormat string
+
+const (
+	// FormatEnvelope renders the {success, code, msg, data, traceId} envelope of Response
+	FormatEnvelope ResponseFormat = "envelope"
+	// FormatProblem renders errors as RFC 7807 problem details and successful data as is
+	FormatProblem ResponseFormat = "problem"
+
+	ProblemContentType = "application/problem+json"
+)
+
+type ResponseConfig struct {
+	Format ResponseFormat
+	// Negotiate switches to problem details for requests accepting application/problem+json
+	Negotiate bool
+	// ProblemTypeBase prefixes the error code to build the problem type URI, about:blank if empty
+	ProblemTypeBase string
+}
+
+var responseConfig = ResponseConfig{
+	Format: FormatEnvelope,
+}
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	TraceID  string `json:"traceId,omitempty"`
+	Errors   any    `json:"errors,omitempty"`
+}
+
+// SetupResponse loads the response format from the response config
+func SetupResponse() {
+	option := ResponseConfig{
+		Format: FormatEnvelope,
+	}
+	err := config.UnmarshalKey("response", &option)
+	if err != nil {
+		logger.Panic(err)
+	}
+	if option.Format != FormatEnvelope && option.Format != FormatProblem {
+		logger.Panicf("Unsupported response format %s", option.Format)
+	}
+
+	responseConfig = option
+	logger.Debugf("Response format: %s, negotiate: %t", option.Format, option.Negotiate)
+}
+
+// DefaultFormat returns the configured response format
+func DefaultFormat() ResponseFormat {
+	return responseConfig.Format
+}
+
+// Format returns the response format of the request
+func Format(c *gin.Context) ResponseFormat {
+	if responseConfig.Negotiate && strings.Contains(c.GetHeader("Accept"), ProblemContentType) {
+		return FormatProblem
+	}
+	return responseConfig.Format
+}
+
+func NewProblem(c *gin.Context, status int, code string, message string) Problem {
+	problemType := "about:blank"
+	if responseConfig.ProblemTypeBase != "" && code != "" {
+		problemType = strings.TrimSuffix(responseConfig.ProblemTypeBase, "/") + "/" + code
+	}
+
+	var instance string
+	if c.Request != nil {
+		instance = c.Request.URL.Path
+	}
+	return Problem{
+		Type:     problemType,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   message,
+		Instance: instance,
+		Code:     code,
+		TraceID:  getTraceID(c),
+	}
+}
+
+// AbortWithError writes a failed response in the format of the request and aborts the chain,
+// fieldErrors is rendered as the data of the envelope or the errors member of problem details
+func AbortWithError(c *gin.Context, status int, code string, message string, fieldErrors any) {
+	if Format(c) == FormatProblem {
+		problem := NewProblem(c, status, code, message)
+		problem.Errors = fieldErrors
+		c.Header("Content-Type", ProblemContentType)
+		c.AbortWithStatusJSON(status, problem)
+		return
+	}
+	resp := NewFailedResponse(c, code, message)
+	resp.Data = fieldErrors
+	c.AbortWithStatusJSON(status, resp)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblem(t *testing.T) {
+	responseConfig = ResponseConfig{Format: FormatEnvelope, Negotiate: true, ProblemTypeBase: "https://errors.example.com/"}
+	defer func() {
+		responseConfig = ResponseConfig{Format: FormatEnvelope}
+	}()
+
+	engine := gin.New()
+	engine.GET("/ok", func(c *gin.Context) {
+		OK(c, map[string]string{"name": "gfa"})
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		AbortWithError(c, http.StatusConflict, "DUPLICATED", "name is taken", []string{"name"})
+	})
+
+	// the envelope stays the default
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	var resp Response[any]
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "DUPLICATED", resp.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	var problem map[string]any
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "https://errors.example.com/DUPLICATED", problem["type"])
+	assert.Equal(t, "Conflict", problem["title"])
+	assert.Equal(t, float64(http.StatusConflict), problem["status"])
+	assert.Equal(t, "name is taken", problem["detail"])
+	assert.Equal(t, "/fail", problem["instance"])
+	assert.Equal(t, []any{"name"}, problem["errors"])
+
+	req = httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("Accept", ProblemContentType)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"name":"gfa"}`, w.Body.String())
+}
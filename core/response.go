@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gfa-inc/gfa/middlewares/requestid"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,8 +24,15 @@ type PaginatedData[T any] struct {
 	Total int64 `json:"total" validate:"required"`
 }
 
+// CursorData is a page of keyset pagination, NextCursor fetches the following page while HasMore is true
+type CursorData[T any] struct {
+	Data       T      `json:"list" validate:"required"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+}
+
 func NewSucceedResponse[T any](c context.Context, data T) Response[T] {
-	traceID, _ := c.Value(requestid.ContextKey).(string)
+	traceID := getTraceID(c)
 	return Response[T]{
 		Success: true,
 		Code:    "0",
@@ -35,7 +43,7 @@ func NewSucceedResponse[T any](c context.Context, data T) Response[T] {
 }
 
 func NewFailedResponse(c context.Context, code string, message string) Response[any] {
-	traceID, _ := c.Value(requestid.ContextKey).(string)
+	traceID := getTraceID(c)
 	return Response[any]{
 		Success: false,
 		Code:    code,
@@ -45,13 +53,37 @@ func NewFailedResponse(c context.Context, code string, message string) Response[
 	}
 }
 
-// OK returns processing result successfully
+// getTraceID prefers the OpenTelemetry trace ID of the request span over the request ID
+func getTraceID(c context.Context) string {
+	ctx := c
+	if gc, ok := c.(*gin.Context); ok && gc.Request != nil {
+		ctx = gc.Request.Context()
+	}
+	if spanCtx := oteltrace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		return spanCtx.TraceID().String()
+	}
+
+	traceID, _ := c.Value(requestid.ContextKey).(string)
+	return traceID
+}
+
+// OK returns processing result successfully, the data is not wrapped in problem format
 func OK(c *gin.Context, data any) {
+	if Format(c) == FormatProblem {
+		c.JSON(http.StatusOK, data)
+		return
+	}
 	c.JSON(http.StatusOK, NewSucceedResponse(c, data))
 }
 
 // Fail returns error code and message
 func Fail(c *gin.Context, code string, message string) {
+	if Format(c) == FormatProblem {
+		problem := NewProblem(c, http.StatusServiceUnavailable, code, message)
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(http.StatusServiceUnavailable, problem)
+		return
+	}
 	c.JSON(http.StatusServiceUnavailable, NewFailedResponse(c, code, message))
 }
 
@@ -59,3 +91,8 @@ func Fail(c *gin.Context, code string, message string) {
 func Paginated[T any](data T, total int64) PaginatedData[T] {
 	return PaginatedData[T]{data, total}
 }
+
+// CursorPaginated returns a page of keyset pagination
+func CursorPaginated[T any](data T, nextCursor string, hasMore bool) CursorData[T] {
+	return CursorData[T]{data, nextCursor, hasMore}
+}
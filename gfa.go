@@ -10,23 +10,23 @@ import (
 	"sync"
 	"syscall"
 	"testing"
+	"time"
 
-	"github.com/gfa-inc/gfa/common/aws"
-	"github.com/gfa-inc/gfa/common/cache"
 	"github.com/gfa-inc/gfa/common/config"
-	"github.com/gfa-inc/gfa/common/db"
+	"github.com/gfa-inc/gfa/common/health"
 	"github.com/gfa-inc/gfa/common/logger"
-	"github.com/gfa-inc/gfa/common/messenger/mailx"
-	"github.com/gfa-inc/gfa/common/mq"
-	"github.com/gfa-inc/gfa/common/nsdb"
+	"github.com/gfa-inc/gfa/common/metrics"
+	"github.com/gfa-inc/gfa/common/metrics/exporter"
+	"github.com/gfa-inc/gfa/common/openapi"
 	"github.com/gfa-inc/gfa/common/swag"
-	"github.com/gfa-inc/gfa/common/validatorx"
 	"github.com/gfa-inc/gfa/core"
 	"github.com/gfa-inc/gfa/middlewares"
 	"github.com/gfa-inc/gfa/middlewares/accesslog"
+	"github.com/gfa-inc/gfa/middlewares/locale"
 	"github.com/gfa-inc/gfa/middlewares/requestid"
 	"github.com/gfa-inc/gfa/middlewares/security"
 	"github.com/gfa-inc/gfa/middlewares/session"
+	"github.com/gfa-inc/gfa/middlewares/tracing"
 	"github.com/gfa-inc/gfa/utils"
 	"github.com/gfa-inc/gfa/utils/syncx"
 	"github.com/gin-contrib/graceful"
@@ -39,11 +39,15 @@ var (
 	cancelWg  sync.WaitGroup
 )
 
+const DefaultShutdownTimeout = 30 // seconds
+
 type Gfa struct {
 	Engine     *gin.Engine
 	mdws       []gin.HandlerFunc
 	setups     []func()
 	postSetups []func()
+	components []Component
+	started    []Component
 
 	controllers []core.Controller
 	ginOpts     []gin.OptionFunc
@@ -94,6 +98,8 @@ func (g *Gfa) Run() {
 	// graceful shutdown
 	cancelCtx, stop = signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
+	// fail readiness as soon as shutdown starts
+	context.AfterFunc(cancelCtx, health.MarkShuttingDown)
 
 	syncx.All(func() {
 		logger.Infof("Listen and Serving HTTP on http://%s", addr)
@@ -104,6 +110,19 @@ func (g *Gfa) Run() {
 	}, func() {
 		cancelWg.Wait()
 	})
+
+	shutdownTimeout := config.GetInt("server.shutdown_timeout")
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(shutdownTimeout)*time.Second)
+	defer cancel()
+	g.stopComponents(shutdownCtx)
+
+	// flush pending spans
+	if err = logger.ShutdownTracing(shutdownCtx); err != nil {
+		logger.Errorf("Fail to shutdown tracing, %s", err)
+	}
 }
 
 func WithGinOption(opts ...gin.OptionFunc) {
@@ -136,6 +155,9 @@ func ParseLoggerConfig() logger.OptionFunc {
 		if err != nil {
 			log.Panic(err)
 		}
+		if option.ServiceName == "" {
+			option.ServiceName = config.GetString("name")
+		}
 	}
 }
 
@@ -150,19 +172,8 @@ func Default() *Gfa {
 		setup()
 	}
 
-	cache.Setup()
-
-	db.Setup()
-
-	nsdb.Setup()
-
-	mq.Setup()
-
-	validatorx.Setup()
-
-	aws.Setup()
-
-	mailx.Setup()
+	// components
+	gfa.startComponents(context.Background())
 
 	// post setups
 	for _, setup := range gfa.postSetups {
@@ -180,15 +191,29 @@ func Default() *Gfa {
 		logger.Debugf("%s %s %s %d", httpMethod, absolutePath, handlerName, nuHandlers)
 	}
 
+	core.SetupResponse()
+
 	gfa.Engine = gin.New(gfa.ginOpts...)
+	// handlers passing the gin context to the layers below share the span and cancellation of the request
+	gfa.Engine.ContextWithFallback = true
 	// recovery
 	gfa.Engine.Use(gin.Recovery())
-	// onerror
-	gfa.Engine.Use(middlewares.OnError())
 	// requestid
 	gfa.Engine.Use(requestid.RequestID())
+	// locale
+	gfa.Engine.Use(locale.Locale())
+	// tracing
+	if logger.IsTracingEnabled() {
+		gfa.Engine.Use(tracing.Tracing())
+	}
 	// access log
 	gfa.Engine.Use(accesslog.AccessLog())
+	// metrics
+	if metrics.Enabled() {
+		gfa.Engine.Use(metrics.Metrics())
+	}
+	// onerror, inside tracing, access log and metrics so they read the status of the error responses
+	gfa.Engine.Use(middlewares.OnError())
 	// session
 	if session.Enabled() {
 		gfa.Engine.Use(session.Session())
@@ -210,6 +235,14 @@ func Run() {
 	rootRouter := gfa.Engine.Group(basePath)
 	// swagger
 	swag.Setup(rootRouter)
+	// openapi
+	openapi.Setup(gfa.Engine, rootRouter)
+	// health
+	health.Setup(rootRouter)
+	// metrics
+	exporter.Setup(rootRouter)
+	// jwks
+	security.Setup(&gfa.Engine.RouterGroup)
 	// custom routes
 	for _, controller := range gfa.controllers {
 		controller.Setup(rootRouter)
@@ -0,0 +1,66 @@
+package locale
+
+import (
+	"context"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/validatorx"
+	"github.com/gfa-inc/gfa/utils/lang"
+	"github.com/gin-gonic/gin"
+	ut "github.com/go-playground/universal-translator"
+)
+
+const ContextKey = lang.ContextKey
+
+type Config struct {
+	// QueryKey and CookieKey take precedence over Accept-Language, empty disables them
+	QueryKey  string
+	CookieKey string
+}
+
+// Locale picks the locale of each request from the query, the cookie or Accept-Language,
+// keeping the first one with a registered validator translator
+func Locale() gin.HandlerFunc {
+	option := Config{
+		QueryKey:  "lang",
+		CookieKey: "lang",
+	}
+	err := config.UnmarshalKey("locale", &option)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	logger.Infof("Locale middleware enabled, query key: %s, cookie key: %s", option.QueryKey, option.CookieKey)
+	return func(c *gin.Context) {
+		candidates := make([]string, 0, 4)
+		if option.QueryKey != "" {
+			candidates = append(candidates, c.Query(option.QueryKey))
+		}
+		if option.CookieKey != "" {
+			cookie, _ := c.Cookie(option.CookieKey)
+			candidates = append(candidates, cookie)
+		}
+		candidates = append(candidates, lang.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+
+		for _, tag := range candidates {
+			if _, ok := validatorx.MatchLocale(tag); ok {
+				c.Set(ContextKey, tag)
+				c.Request = c.Request.WithContext(lang.WithLocale(c.Request.Context(), tag))
+				break
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Get returns the locale chosen for the request, empty if none matched
+func Get(ctx context.Context) string {
+	return lang.Get(ctx)
+}
+
+// Translator returns the validator translator of the request
+func Translator(ctx context.Context) ut.Translator {
+	return validatorx.GetTranslator(Get(ctx))
+}
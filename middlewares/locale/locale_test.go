@@ -0,0 +1,50 @@
+package locale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/validatorx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocale(t *testing.T) {
+	config.Setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+	validatorx.Setup()
+
+	engine := gin.New()
+	engine.Use(Locale())
+	engine.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, Get(c)+"|"+Get(c.Request.Context())+"|"+Translator(c).Locale())
+	})
+
+	cases := []struct {
+		target   string
+		language string
+		cookie   string
+		expected string
+	}{
+		{"/", "fr-FR, en-GB;q=0.9", "", "en-GB|en-GB|en"},
+		{"/", "zh-CN", "", "zh-CN|zh-CN|zh"},
+		{"/?lang=en", "zh-CN", "", "en|en|en"},
+		{"/", "zh-CN", "en", "en|en|en"},
+		{"/", "fr", "", "||zh"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		req.Header.Set("Accept-Language", tc.language)
+		if tc.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "lang", Value: tc.cookie})
+		}
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, tc.expected, w.Body.String(), tc.target+" "+tc.language)
+	}
+}
@@ -1,11 +1,13 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gfa-inc/gfa/common/logger"
 	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares/locale"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,23 +21,79 @@ func OnError() gin.HandlerFunc {
 			return
 		}
 
-		logger.Error(c.Errors.String())
-		for _, err := range c.Errors {
-			switch e := err.Err.(type) {
-			case *core.ParamErr:
-				c.AbortWithStatusJSON(http.StatusBadRequest,
-					core.NewFailedResponse(c, strconv.Itoa(http.StatusBadRequest), e.Error()))
-			case *core.BizErr:
-				c.AbortWithStatusJSON(http.StatusOK, core.NewFailedResponse(c, e.Code, e.Message))
-			case *core.AuthErr:
-				c.AbortWithStatusJSON(http.StatusForbidden,
-					core.NewFailedResponse(c, strconv.Itoa(http.StatusForbidden), e.Error()))
-			case *core.UnauthorizedErr:
-				c.AbortWithStatus(http.StatusUnauthorized)
-			default:
-				c.AbortWithStatusJSON(http.StatusOK, core.NewFailedResponse(c, "500", e.Error()))
-			}
+		err := c.Errors[0].Err
+		r := resolve(c, err)
+		logError(c, r.level, c.Errors.String())
+
+		// unauthorized errors without a reason keep the bare 401 of the envelope format
+		var unauthorizedErr *core.UnauthorizedErr
+		if errors.As(err, &unauthorizedErr) && unauthorizedErr.Err == nil && core.Format(c) == core.FormatEnvelope {
+			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		core.AbortWithError(c, r.status, r.code, r.message, r.fields)
+	}
+}
+
+type resolved struct {
+	status  int
+	code    string
+	message string
+	level   string
+	fields  any
+}
+
+// resolve maps an error, possibly wrapped, to its response and log level
+func resolve(c *gin.Context, err error) resolved {
+	var (
+		paramErr *core.ParamErr
+		bizErr   *core.BizErr
+		authErr  *core.AuthErr
+	)
+	// failed authentications stay 401 whatever their reason wraps
+	switch {
+	case errors.As(err, new(*core.UnauthorizedErr)):
+		return resolved{http.StatusUnauthorized, strconv.Itoa(http.StatusUnauthorized), err.Error(), "warn", nil}
+	case errors.As(err, &paramErr):
+		paramErr = paramErr.Localize(locale.Translator(c))
+		r := resolved{http.StatusBadRequest, strconv.Itoa(http.StatusBadRequest), paramErr.Error(), "warn", nil}
+		if len(paramErr.Fields) > 0 {
+			r.fields = paramErr.Fields
+		}
+		return r
+	case errors.As(err, &bizErr):
+		r := resolved{http.StatusOK, bizErr.Code, bizErr.Message, "error", nil}
+		if def, ok := core.LookupErr(bizErr.Code); ok {
+			message := def.Message(core.Locale(c), bizErr.Args...)
+			if message == "" {
+				message = bizErr.Message
+			}
+			r = resolved{def.Status, def.Code, message, def.Level, nil}
+		}
+		// problem details are errors, business errors answered with a success status in the envelope are unprocessable
+		if r.status < http.StatusBadRequest && core.Format(c) == core.FormatProblem {
+			r.status = http.StatusUnprocessableEntity
+		}
+		return r
+	case errors.As(err, &authErr):
+		return resolved{http.StatusForbidden, strconv.Itoa(http.StatusForbidden), authErr.Error(), "warn", nil}
+	default:
+		if core.Format(c) == core.FormatProblem {
+			return resolved{http.StatusInternalServerError, "500", err.Error(), "error", nil}
+		}
+		return resolved{http.StatusOK, "500", err.Error(), "error", nil}
+	}
+}
+
+func logError(c *gin.Context, level string, message string) {
+	switch level {
+	case "debug":
+		logger.TDebug(c, message)
+	case "info":
+		logger.TInfo(c, message)
+	case "warn":
+		logger.TWarn(c, message)
+	default:
+		logger.TError(c, message)
 	}
 }
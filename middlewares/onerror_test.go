@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/common/validatorx"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares/locale"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var errOrderNotFound = core.DefineErr(core.ErrDef{
+	Code:   "ORDER_NOT_FOUND",
+	Status: http.StatusNotFound,
+	Messages: map[string]string{
+		"zh": "订单 %d 不存在",
+		"en": "order %d not found",
+	},
+})
+
+func TestOnError(t *testing.T) {
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+	})
+
+	engine := gin.New()
+	engine.Use(OnError())
+	engine.GET("/order", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("load order: %w", errOrderNotFound.WithField(7)))
+	})
+	engine.GET("/param", func(c *gin.Context) {
+		_ = c.Error(core.NewParamErr("name is required"))
+	})
+	engine.GET("/biz", func(c *gin.Context) {
+		_ = c.Error(core.NewBizErr("1001", "unregistered"))
+	})
+	engine.GET("/unknown", func(c *gin.Context) {
+		_ = c.Error(errors.New("boom"))
+	})
+	engine.GET("/unauthorized", func(c *gin.Context) {
+		_ = c.Error(core.NewUnauthorizedErr().WithErr(core.NewAuthErr("token of another tenant")))
+	})
+
+	cases := []struct {
+		path     string
+		language string
+		status   int
+		code     string
+		message  string
+	}{
+		{"/order", "en-US,en;q=0.9", http.StatusNotFound, "ORDER_NOT_FOUND", "order 7 not found"},
+		{"/order", "", http.StatusNotFound, "ORDER_NOT_FOUND", "订单 7 不存在"},
+		{"/param", "", http.StatusBadRequest, "400", "name is required"},
+		{"/biz", "", http.StatusOK, "1001", "unregistered"},
+		{"/unknown", "", http.StatusOK, "500", "boom"},
+		{"/unauthorized", "", http.StatusUnauthorized, "401", "token of another tenant"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.language != "" {
+			req.Header.Set("Accept-Language", tc.language)
+		}
+		engine.ServeHTTP(w, req)
+
+		var resp core.Response[any]
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		assert.Equal(t, tc.code, resp.Code, tc.path)
+		assert.Equal(t, tc.message, resp.Message, tc.path)
+	}
+}
+
+func TestOnErrorProblem(t *testing.T) {
+	config.Setup()
+	config.SetDefault("response.format", "problem")
+	core.SetupResponse()
+	defer func() {
+		config.Setup()
+		core.SetupResponse()
+	}()
+
+	engine := gin.New()
+	engine.Use(OnError())
+	engine.GET("/order", func(c *gin.Context) {
+		_ = c.Error(errOrderNotFound.WithField(7))
+	})
+	engine.GET("/login", func(c *gin.Context) {
+		_ = c.Error(core.NewUnauthorizedErr())
+	})
+	engine.GET("/biz", func(c *gin.Context) {
+		_ = c.Error(core.NewBizErr("1001", "unregistered"))
+	})
+	engine.GET("/unknown", func(c *gin.Context) {
+		_ = c.Error(errors.New("boom"))
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order", nil))
+
+	var problem core.Problem
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, core.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "ORDER_NOT_FOUND", problem.Code)
+	assert.Equal(t, "订单 7 不存在", problem.Detail)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+
+	// errors answered with 200 in the envelope get an error status
+	for path, status := range map[string]int{"/biz": http.StatusUnprocessableEntity, "/unknown": http.StatusInternalServerError} {
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		problem = core.Problem{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, status, w.Code, path)
+		assert.Equal(t, status, problem.Status, path)
+		assert.Equal(t, http.StatusText(status), problem.Title, path)
+	}
+}
+
+type orderItem struct {
+	SKU string `json:"sku" binding:"required"`
+	Qty int    `json:"qty" binding:"min=1"`
+}
+
+type createOrder struct {
+	Name  string      `json:"name" binding:"required"`
+	Items []orderItem `json:"items" binding:"required,dive"`
+}
+
+func TestOnErrorFields(t *testing.T) {
+	validatorx.Setup()
+
+	engine := gin.New()
+	engine.Use(OnError())
+	engine.POST("/order", func(c *gin.Context) {
+		var req createOrder
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(core.NewParamErr(err))
+		}
+	})
+
+	w := httptest.NewRecorder()
+	body := `{"items":[{"sku":"a","qty":1},{"sku":"b","qty":0},{"qty":2}]}`
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body)))
+
+	var resp core.Response[[]core.FieldError]
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, resp.Data, 3)
+	assert.Equal(t, "name", resp.Data[0].Field)
+	assert.Equal(t, "required", resp.Data[0].Tag)
+	assert.Equal(t, "items[1].qty", resp.Data[1].Field)
+	assert.Equal(t, "min", resp.Data[1].Tag)
+	assert.Equal(t, "1", resp.Data[1].Param)
+	assert.Equal(t, "items[2].sku", resp.Data[2].Field)
+	assert.NotEmpty(t, resp.Data[2].Message)
+	assert.Contains(t, resp.Message, resp.Data[2].Message)
+
+	// field errors follow the locale of the request
+	engine = gin.New()
+	engine.Use(locale.Locale(), OnError())
+	engine.POST("/order", func(c *gin.Context) {
+		var req createOrder
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(core.NewParamErr(err))
+		}
+	})
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"items":[]}`))
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	engine.ServeHTTP(w, req)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "name is a required field", resp.Data[0].Message)
+}
@@ -1,6 +1,7 @@
 package requestid
 
 import (
+	"context"
 	"strings"
 
 	"github.com/gfa-inc/gfa/common/config"
@@ -10,7 +11,12 @@ import (
 	"github.com/google/uuid"
 )
 
-var ContextKey string
+var (
+	ContextKey string
+	HeaderKey  string
+)
+
+type requestIDKey struct{}
 
 type Config struct {
 	HeaderKey  string
@@ -28,6 +34,7 @@ func RequestID() gin.HandlerFunc {
 	}
 
 	ContextKey = option.ContextKey
+	HeaderKey = option.HeaderKey
 
 	// record traceID in log
 	logger.AddContextKey(ContextKey)
@@ -40,5 +47,19 @@ func RequestID() gin.HandlerFunc {
 		}),
 		requestid.WithHandler(func(c *gin.Context, requestID string) {
 			c.Set(option.ContextKey, requestID)
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, requestID))
 		}))
 }
+
+// Get returns the request ID carried by a gin context or by the context of its request
+func Get(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return requestid.Get(c)
+	}
+
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -80,6 +80,11 @@ func (v *Validator) parseLookup() {
 	}
 }
 
+// Lookups returns the parsed API key lookup locations as [location, key] pairs, e.g. [header, X-Api-Key]
+func (v *Validator) Lookups() [][2]string {
+	return v.lookupMap
+}
+
 // Valid validates API key
 func (v *Validator) Valid(c *gin.Context) error {
 	apiKey, err := v.extractApiKey(c)
@@ -1,14 +1,18 @@
 package jwtx
 
 import (
+	"crypto"
 	"errors"
 	"strings"
 	"time"
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/utils/principal"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -36,7 +40,15 @@ type Config struct {
 	AutoRefresh      bool   `mapstructure:"auto_refresh"`      // Enable auto token refresh
 	RefreshThreshold int64  `mapstructure:"refresh_threshold"` // Refresh threshold in seconds, time remaining before expiration to trigger refresh
 	RefreshHeader    string `mapstructure:"refresh_header"`    // Response header name for new token
-	SigningMethod    string `mapstructure:"signing_method"`    // Signing algorithm HS256/HS384/HS512
+	SigningMethod    string `mapstructure:"signing_method"`    // Signing algorithm HS256/HS384/HS512, RS256/RS384/RS512, PS256/PS384/PS512, ES256/ES384/ES512 or EdDSA
+	// Keys of the asymmetric signing methods chosen by kid, keep the retired keys until their tokens expire
+	Keys       []KeyConfig `mapstructure:"keys"`
+	SigningKid string      `mapstructure:"signing_kid"` // Kid of the key signing new tokens, default the first key with a private key
+	JWKSPath   string      `mapstructure:"jwks_path"`   // Path serving the public keys, e.g. /.well-known/jwks.json, disabled when empty
+	// OIDC verifies the tokens of an external identity provider, tokens are no longer generated nor refreshed
+	OIDC OIDCConfig `mapstructure:"oidc"`
+	// Revocation denies revoked tokens and enables refresh tokens, backed by redis
+	Revocation RevocationConfig `mapstructure:"revocation"`
 }
 
 // Validator JWT validator
@@ -44,6 +56,11 @@ type Validator struct {
 	config         Config
 	tokenLookupMap [][2]string
 	signingMethod  jwt.SigningMethod
+	signingKey     crypto.Signer
+	signingKid     string
+	keys           map[string]*verifyKey
+	oidc           *oidcProvider
+	redisClient    redis.UniversalClient
 }
 
 // Claims JWT claims structure
@@ -51,6 +68,12 @@ type Claims struct {
 	UserID   string                 `json:"user_id,omitempty"`
 	Username string                 `json:"username,omitempty"`
 	Data     map[string]interface{} `json:"data,omitempty"`
+	// SessionID identifies the tokens of a login issued by IssueTokens
+	SessionID string `json:"sid,omitempty"`
+	// TokenUse is refresh for refresh tokens, which are not accepted as access tokens
+	TokenUse string `json:"token_use,omitempty"`
+	// IssuedAtNano is the issue time in nanoseconds, ordering the tokens issued in the same second as RevokeAll
+	IssuedAtNano int64 `json:"iat_ns,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -61,7 +84,8 @@ func Default() *Validator {
 	return jv
 }
 
-func New(cfg Config) *Validator {
+// New creates a JWT validator, the redis client of revocation defaults to the one configured in redisx
+func New(cfg Config, redisClient ...redis.UniversalClient) *Validator {
 	if cfg.ExpireTime == 0 {
 		cfg.ExpireTime = DefaultExpireTime
 	}
@@ -83,6 +107,17 @@ func New(cfg Config) *Validator {
 	}
 	jv.parseTokenLookup()
 	jv.parseSigningMethod()
+	if err := jv.loadKeys(); err != nil {
+		logger.Panicf("Failed to load JWT keys: %v", err)
+	}
+	if cfg.OIDC.Issuer != "" {
+		jv.oidc = newOIDCProvider(cfg.OIDC)
+	} else if !isSymmetric(jv.signingMethod) && jv.signingKey == nil {
+		logger.Panicf("JWT signing method %s requires keys", jv.signingMethod.Alg())
+	}
+	if cfg.Revocation.Enable {
+		jv.setupRevocation(redisClient...)
+	}
 	return jv
 }
 
@@ -114,13 +149,15 @@ func (j *Validator) parseTokenLookup() {
 	}
 }
 
+// Lookups returns the parsed token lookup locations as [location, key] pairs, e.g. [header, Authorization]
+func (j *Validator) Lookups() [][2]string {
+	return j.tokenLookupMap
+}
+
 func (j *Validator) parseSigningMethod() {
-	switch j.config.SigningMethod {
-	case "HS384":
-		j.signingMethod = jwt.SigningMethodHS384
-	case "HS512":
-		j.signingMethod = jwt.SigningMethodHS512
-	default:
+	j.signingMethod = jwt.GetSigningMethod(j.config.SigningMethod)
+	if j.signingMethod == nil || j.signingMethod == jwt.SigningMethodNone {
+		logger.Warnf("Unsupported JWT signing method %s, fallback to %s", j.config.SigningMethod, DefaultSigningMethod)
 		j.signingMethod = jwt.SigningMethodHS256
 	}
 }
@@ -138,12 +175,20 @@ func (j *Validator) Valid(c *gin.Context) error {
 		return err
 	}
 
+	if claims.TokenUse == TokenUseRefresh {
+		return ErrJwtInvalidToken
+	}
+	if err = j.checkRevoked(c.Request.Context(), claims); err != nil {
+		return err
+	}
+
 	// Store claims and original token in context
 	c.Set(JwtContextKey, claims)
 	c.Set(JwtTokenContextKey, tokenString)
+	c.Set(principal.UserIDKey, claims.UserID)
 
-	// Check for auto-refresh
-	if j.config.AutoRefresh {
+	// Check for auto-refresh, tokens of the identity provider are refreshed by their clients
+	if j.config.AutoRefresh && j.oidc == nil {
 		j.checkAndRefresh(c, claims)
 	}
 
@@ -178,13 +223,19 @@ func (j *Validator) extractToken(c *gin.Context) (string, error) {
 
 // ParseToken parses JWT token
 func (j *Validator) ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if token.Method != j.signingMethod {
-			return nil, errors.New("invalid signing method")
+	if j.oidc != nil {
+		claims, err := j.oidc.parse(tokenString)
+		if err != nil {
+			logger.Debugf("Invalid token of %s: %v", j.config.OIDC.Issuer, err)
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				return nil, ErrJwtExpired
+			}
+			return nil, ErrJwtInvalidToken
 		}
-		return []byte(j.config.PrivateKey), nil
-	})
+		return claims, nil
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFunc)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -202,38 +253,37 @@ func (j *Validator) ParseToken(tokenString string) (*Claims, error) {
 
 // GenerateToken generates a JWT token
 func (j *Validator) GenerateToken(userID, username string, data map[string]interface{}) (string, error) {
-	now := time.Now()
-	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Data:     data,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(j.config.ExpireTime) * time.Second)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-		},
+	if j.oidc != nil {
+		return "", ErrJwtExternalIssuer
 	}
-
-	token := jwt.NewWithClaims(j.signingMethod, claims)
-	return token.SignedString([]byte(j.config.PrivateKey))
+	return j.sign(j.newClaims(userID, username, data, j.config.ExpireTime))
 }
 
-// RefreshToken refreshes an existing token
+// RefreshToken refreshes an existing token, keeping the login of tokens issued by IssueTokens
 func (j *Validator) RefreshToken(oldClaims *Claims) (string, error) {
+	if j.oidc != nil {
+		return "", ErrJwtExternalIssuer
+	}
+	newClaims := j.newClaims(oldClaims.UserID, oldClaims.Username, oldClaims.Data, j.config.ExpireTime)
+	newClaims.SessionID = oldClaims.SessionID
+	return j.sign(newClaims)
+}
+
+// newClaims builds the claims of a token expiring in expireTime seconds, with a unique jti to revoke it
+func (j *Validator) newClaims(userID, username string, data map[string]interface{}, expireTime int64) *Claims {
 	now := time.Now()
-	newClaims := &Claims{
-		UserID:   oldClaims.UserID,
-		Username: oldClaims.Username,
-		Data:     oldClaims.Data,
+	return &Claims{
+		UserID:       userID,
+		Username:     username,
+		Data:         data,
+		IssuedAtNano: now.UnixNano(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(j.config.ExpireTime) * time.Second)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expireTime) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
-
-	token := jwt.NewWithClaims(j.signingMethod, newClaims)
-	return token.SignedString([]byte(j.config.PrivateKey))
 }
 
 // checkAndRefresh checks and performs auto-refresh if needed
@@ -363,13 +413,36 @@ func WithRefreshHeader(header string) Option {
 	}
 }
 
-// WithSigningMethod sets the signing algorithm (HS256/HS384/HS512)
+// WithSigningMethod sets the signing algorithm (HS256/HS384/HS512, RS256, ES256, EdDSA...)
 func WithSigningMethod(method string) Option {
 	return func(c *Config) {
 		c.SigningMethod = method
 	}
 }
 
+// WithKeys sets the keys of the asymmetric signing methods, the first key with a private key signs new tokens
+func WithKeys(keys ...KeyConfig) Option {
+	return func(c *Config) {
+		c.Keys = keys
+	}
+}
+
+// WithOIDC verifies the tokens of an external identity provider instead of generating tokens
+func WithOIDC(oidc OIDCConfig) Option {
+	return func(c *Config) {
+		c.OIDC = oidc
+	}
+}
+
+// WithRevocation enables revocation and refresh tokens backed by the redisx client of the name,
+// the default client when empty
+func WithRevocation(redisName string) Option {
+	return func(c *Config) {
+		c.Revocation.Enable = true
+		c.Revocation.Redis = redisName
+	}
+}
+
 // Middleware creates JWT middleware
 // Used to protect specific routes, requires request to have a valid JWT token
 // opts: optional configuration using WithXXX functions
@@ -1,15 +1,32 @@
 package jwtx
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/utils/principal"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -349,3 +366,359 @@ func createExpiredToken(t *testing.T, jv *Validator) string {
 
 	return tokenString
 }
+
+func pemKeys(t *testing.T, key crypto.Signer) (string, string) {
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+}
+
+func TestJwtValidator_AsymmetricSigningMethods(t *testing.T) {
+	setupTest()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		method string
+		key    crypto.Signer
+	}{
+		{"RS256", rsaKey},
+		{"PS256", rsaKey},
+		{"ES256", ecKey},
+		{"EdDSA", edKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			private, _ := pemKeys(t, tc.key)
+			file := filepath.Join(t.TempDir(), "key.pem")
+			assert.NoError(t, os.WriteFile(file, []byte(private), 0600))
+
+			jv := New(Config{SigningMethod: tc.method, Keys: []KeyConfig{{Kid: "k1", PrivateKeyFile: file}}})
+			token, err := jv.GenerateToken("user123", "john_doe", nil)
+			assert.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			assert.NoError(t, err)
+			assert.Equal(t, "k1", parsed.Header["kid"])
+			assert.Equal(t, tc.method, parsed.Header["alg"])
+
+			claims, err := jv.ParseToken(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user123", claims.UserID)
+		})
+	}
+}
+
+func TestJwtValidator_KeyRotation(t *testing.T) {
+	setupTest()
+
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	oldPrivate, oldPublic := pemKeys(t, oldKey)
+	newPrivate, _ := pemKeys(t, newKey)
+
+	before := New(Config{SigningMethod: "ES256", Keys: []KeyConfig{{Kid: "2024", PrivateKey: oldPrivate}}})
+	oldToken, err := before.GenerateToken("user123", "john_doe", nil)
+	assert.NoError(t, err)
+
+	after := New(Config{
+		SigningMethod: "RS256",
+		SigningKid:    "2025",
+		Keys: []KeyConfig{
+			{Kid: "2024", Algorithm: "ES256", PublicKey: oldPublic},
+			{Kid: "2025", PrivateKey: newPrivate},
+		},
+	})
+	claims, err := after.ParseToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "user123", claims.UserID)
+
+	newToken, err := after.GenerateToken("user456", "jane_doe", nil)
+	assert.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2025", parsed.Header["kid"])
+
+	_, err = before.ParseToken(newToken)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	// a token of a retired key no longer configured
+	retired := New(Config{SigningMethod: "RS256", Keys: []KeyConfig{{Kid: "2025", PrivateKey: newPrivate}}})
+	_, err = retired.ParseToken(oldToken)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	// a token forged with the shared secret of HS256 is rejected
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "admin"}).SignedString([]byte(oldPublic))
+	assert.NoError(t, err)
+	_, err = after.ParseToken(forged)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	jwks := after.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{Kty: "EC", Kid: "2024", Use: "sig", Alg: "ES256", Crv: "P-256",
+		X: base64.RawURLEncoding.EncodeToString(oldKey.X.FillBytes(make([]byte, 32))),
+		Y: base64.RawURLEncoding.EncodeToString(oldKey.Y.FillBytes(make([]byte, 32))),
+	}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(newKey.N.Bytes()), jwks.Keys[1].N)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", after.JWKSHandler())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var served JWKS
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, jwks, served)
+}
+
+// stubIssuer serves the discovery document and the JWKS of its keys
+type stubIssuer struct {
+	*httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	s := &stubIssuer{keys: make(map[string]*rsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": s.URL, "jwks_uri": s.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		set := JWKS{}
+		for kid, key := range s.keys {
+			jwk, _ := toJWK(&verifyKey{kid: kid, method: jwt.SigningMethodRS256, public: &key.PublicKey})
+			set.Keys = append(set.Keys, jwk)
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubIssuer) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	s.keys[kid] = key
+}
+
+func (s *stubIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(s.keys[kid])
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestJwtValidator_OIDC(t *testing.T) {
+	setupTest()
+
+	issuer := newStubIssuer(t)
+	issuer.addKey(t, "k1")
+	jv := New(Config{OIDC: OIDCConfig{
+		Issuer:   issuer.URL,
+		Audience: []string{"gfa"},
+		Claims: ClaimsMapping{
+			UserID: "uid",
+			Data:   map[string]string{"roles": "realm_access.roles", "tenant": "https://gfa.io/tenant"},
+		},
+	}})
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		mc := jwt.MapClaims{
+			"iss":                   issuer.URL,
+			"aud":                   []string{"account", "gfa"},
+			"sub":                   "f3b1c2",
+			"uid":                   1234567890,
+			"preferred_username":    "alice",
+			"realm_access":          map[string]any{"roles": []string{"admin"}},
+			"https://gfa.io/tenant": "t1",
+			"iat":                   now.Unix(),
+			"exp":                   now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			mc[k] = v
+		}
+		return mc
+	}
+
+	parsed, err := jv.ParseToken(issuer.token(t, "k1", claims(nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890", parsed.UserID)
+	assert.Equal(t, "alice", parsed.Username)
+	assert.Equal(t, []any{"admin"}, parsed.Data["roles"])
+	assert.Equal(t, "t1", parsed.Data["tenant"])
+	assert.Equal(t, "f3b1c2", parsed.Subject)
+	assert.Equal(t, issuer.URL, parsed.Issuer)
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+issuer.token(t, "k1", claims(nil)))
+	assert.NoError(t, jv.Valid(c))
+	assert.Equal(t, "1234567890", GetUserID(c))
+	assert.Equal(t, "1234567890", principal.UserID(c))
+	assert.Equal(t, 1, int(issuer.fetches.Load()))
+
+	// expiration and not before are checked with the clock skew
+	_, err = jv.ParseToken(issuer.token(t, "k1", claims(jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()})))
+	assert.NoError(t, err)
+	_, err = jv.ParseToken(issuer.token(t, "k1", claims(jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()})))
+	assert.ErrorIs(t, err, ErrJwtExpired)
+	_, err = jv.ParseToken(issuer.token(t, "k1", claims(jwt.MapClaims{"nbf": now.Add(2 * time.Minute).Unix()})))
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	for name, overrides := range map[string]jwt.MapClaims{
+		"issuer":     {"iss": "https://evil.example.com"},
+		"audience":   {"aud": "account"},
+		"expiration": {"exp": nil},
+	} {
+		mc := claims(overrides)
+		for k, v := range mc {
+			if v == nil {
+				delete(mc, k)
+			}
+		}
+		_, err = jv.ParseToken(issuer.token(t, "k1", mc))
+		assert.ErrorIs(t, err, ErrJwtInvalidToken, name)
+	}
+
+	// a token signed by a key not published by the issuer
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims(nil))
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(forged)
+	assert.NoError(t, err)
+	_, err = jv.ParseToken(signed)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	// keys rotated by the issuer are fetched on their first token
+	assert.Equal(t, 1, int(issuer.fetches.Load()))
+	defaultInterval := minRefreshInterval
+	minRefreshInterval = 0
+	defer func() {
+		minRefreshInterval = defaultInterval
+	}()
+	issuer.addKey(t, "k2")
+	_, err = jv.ParseToken(issuer.token(t, "k2", claims(nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, int(issuer.fetches.Load()))
+
+	_, err = jv.GenerateToken("user123", "john_doe", nil)
+	assert.ErrorIs(t, err, ErrJwtExternalIssuer)
+}
+
+func TestJwtValidator_Revocation(t *testing.T) {
+	setupTest()
+
+	mr := miniredis.RunT(t)
+	jv := New(Config{PrivateKey: "test-secret-key", Revocation: RevocationConfig{Enable: true}},
+		redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+	valid := func(token string) (*gin.Context, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+		return c, jv.Valid(c)
+	}
+
+	// logout denies the token until it expires
+	token, err := jv.GenerateToken("user123", "john_doe", nil)
+	assert.NoError(t, err)
+	c, err := valid(token)
+	assert.NoError(t, err)
+	claims, _ := GetClaims(c)
+	assert.NotEmpty(t, claims.ID)
+	assert.NoError(t, jv.Logout(c))
+	_, err = valid(token)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+	assert.InDelta(t, DefaultExpireTime, mr.TTL("jwt:revoked:"+claims.ID).Seconds(), 5)
+
+	// refresh tokens are rotated once, a reuse revokes the whole family
+	pair, err := jv.IssueTokens(ctx, "user123", "john_doe", map[string]interface{}{"role": "admin"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(DefaultExpireTime), pair.ExpiresIn)
+	_, err = valid(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+	_, err = jv.Refresh(ctx, pair.AccessToken)
+	assert.ErrorIs(t, err, ErrJwtInvalidToken)
+
+	rotated, err := jv.Refresh(ctx, pair.RefreshToken)
+	assert.NoError(t, err)
+	c, err = valid(rotated.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", c.MustGet(JwtContextKey).(*Claims).Data["role"])
+
+	_, err = jv.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrJwtRefreshReused)
+	_, err = valid(rotated.AccessToken)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+	_, err = jv.Refresh(ctx, rotated.RefreshToken)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+
+	// logout revokes the family of the access token
+	pair, err = jv.IssueTokens(ctx, "user123", "john_doe", nil)
+	assert.NoError(t, err)
+	c, err = valid(pair.AccessToken)
+	assert.NoError(t, err)
+	assert.NoError(t, jv.Logout(c))
+	_, err = jv.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+
+	// revoking all sessions rejects the tokens issued before
+	pair, err = jv.IssueTokens(ctx, "user123", "john_doe", nil)
+	assert.NoError(t, err)
+	token, err = jv.GenerateToken("user456", "jane_doe", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, jv.RevokeAll(ctx, "user123"))
+	_, err = valid(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+	_, err = jv.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+	_, err = valid(token)
+	assert.NoError(t, err)
+
+	// tokens issued right after, e.g. in the same second when the password is changed, are accepted
+	pair, err = jv.IssueTokens(ctx, "user123", "john_doe", nil)
+	assert.NoError(t, err)
+	_, err = valid(pair.AccessToken)
+	assert.NoError(t, err)
+	rotated, err = jv.Refresh(ctx, pair.RefreshToken)
+	assert.NoError(t, err)
+	_, err = valid(rotated.AccessToken)
+	assert.NoError(t, err)
+
+	// tokens without iat_ns are rejected when issued in the same second
+	legacy := jv.newClaims("user123", "john_doe", nil, DefaultExpireTime)
+	legacy.IssuedAtNano = 0
+	token, err = jv.sign(legacy)
+	assert.NoError(t, err)
+	assert.NoError(t, jv.RevokeAll(ctx, "user123"))
+	_, err = valid(token)
+	assert.ErrorIs(t, err, ErrJwtRevoked)
+	token, err = jv.GenerateToken("user456", "jane_doe", nil)
+	assert.NoError(t, err)
+
+	// tokens are rejected when the revocation cannot be checked
+	mr.Close()
+	_, err = valid(token)
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrJwtUnknownKey = errors.New("unknown jwt key id")
+
+// KeyConfig is a PEM key of the asymmetric signing methods, keys with only a public key verify tokens
+// signed before a rotation, the PEM can be inline or read from a file
+type KeyConfig struct {
+	Kid            string `mapstructure:"kid"`              // Key ID written to the kid header
+	Algorithm      string `mapstructure:"algorithm"`        // Signing algorithm of the key, default signing_method
+	PrivateKey     string `mapstructure:"private_key"`      // PEM private key
+	PrivateKeyFile string `mapstructure:"private_key_file"` // PEM private key file
+	PublicKey      string `mapstructure:"public_key"`       // PEM public key, derived from the private key when empty
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // PEM public key file
+}
+
+// verifyKey is a key verifying the tokens of its kid
+type verifyKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func isSymmetric(method jwt.SigningMethod) bool {
+	_, ok := method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// loadKeys parses the PEM keys of the config, the signing key is the one of signing_kid or the first private key
+func (j *Validator) loadKeys() error {
+	j.keys = make(map[string]*verifyKey, len(j.config.Keys))
+	for i, kc := range j.config.Keys {
+		method := j.signingMethod
+		if kc.Algorithm != "" {
+			method = jwt.GetSigningMethod(kc.Algorithm)
+			if method == nil || isSymmetric(method) {
+				return fmt.Errorf("unsupported algorithm %s of jwt key %s", kc.Algorithm, kc.Kid)
+			}
+		}
+		if kc.Kid == "" && len(j.config.Keys) > 1 {
+			return fmt.Errorf("kid of jwt key %d required", i)
+		}
+		if _, ok := j.keys[kc.Kid]; ok {
+			return fmt.Errorf("duplicate jwt key %s", kc.Kid)
+		}
+
+		private, err := readPEM(kc.PrivateKey, kc.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		public, err := readPEM(kc.PublicKey, kc.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+
+		key := &verifyKey{kid: kc.Kid, method: method}
+		if private != nil {
+			signer, err := parsePrivateKey(method, private)
+			if err != nil {
+				return fmt.Errorf("jwt key %s: %w", kc.Kid, err)
+			}
+			key.public = signer.Public()
+			if j.signingKey == nil && (j.config.SigningKid == "" || j.config.SigningKid == kc.Kid) {
+				j.signingKey, j.signingKid, j.signingMethod = signer, kc.Kid, method
+			}
+		}
+		if public != nil {
+			key.public, err = parsePublicKey(method, public)
+			if err != nil {
+				return fmt.Errorf("jwt key %s: %w", kc.Kid, err)
+			}
+		}
+		if key.public == nil {
+			return fmt.Errorf("no private or public key of jwt key %s", kc.Kid)
+		}
+		j.keys[kc.Kid] = key
+	}
+
+	if len(j.keys) > 0 && j.signingKey == nil {
+		return fmt.Errorf("no private key of signing jwt key %s", j.config.SigningKid)
+	}
+	return nil
+}
+
+func readPEM(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
+
+func parsePrivateKey(method jwt.SigningMethod, data []byte) (crypto.Signer, error) {
+	var (
+		key any
+		err error
+	)
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		key, err = jwt.ParseECPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodEd25519:
+		key, err = jwt.ParseEdPrivateKeyFromPEM(data)
+	default:
+		return nil, fmt.Errorf("unsupported signing method %s", method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key.(crypto.Signer), nil
+}
+
+func parsePublicKey(method jwt.SigningMethod, data []byte) (crypto.PublicKey, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM(data)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPublicKeyFromPEM(data)
+	default:
+		return nil, fmt.Errorf("unsupported signing method %s", method.Alg())
+	}
+}
+
+// keyFunc returns the key verifying a token, asymmetric tokens are verified by the key of their kid
+func (j *Validator) keyFunc(token *jwt.Token) (any, error) {
+	if len(j.keys) == 0 {
+		if token.Method != j.signingMethod {
+			return nil, errors.New("invalid signing method")
+		}
+		return []byte(j.config.PrivateKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, ErrJwtUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.public, nil
+}
+
+// sign signs the claims with the signing key, writing its kid to the header
+func (j *Validator) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(j.signingMethod, claims)
+	if j.signingKey == nil {
+		return token.SignedString([]byte(j.config.PrivateKey))
+	}
+	if j.signingKid != "" {
+		token.Header["kid"] = j.signingKid
+	}
+	return token.SignedString(j.signingKey)
+}
+
+// JWKS returns the public keys of the asymmetric signing methods
+func (j *Validator) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(j.keys))}
+	for _, kc := range j.config.Keys {
+		key := j.keys[kc.Kid]
+		if jwk, ok := toJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKSHandler serves the JWKS so other services can verify the tokens without sharing a secret
+func (j *Validator) JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, j.JWKS())
+	}
+}
+
+// JWKSPath returns the path of the JWKS endpoint, empty if disabled
+func (j *Validator) JWKSPath() string {
+	return j.config.JWKSPath
+}
+
+func toJWK(key *verifyKey) (JWK, bool) {
+	jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64(public.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encodeBase64(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// PublicKey parses the public key of a JWK of type RSA, EC or OKP
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var (
+			curve elliptic.Curve
+			ec    ecdh.Curve
+		)
+		switch k.Crv {
+		case "P-256":
+			curve, ec = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ec = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ec = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s of jwk %s", k.Crv, k.Kid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid coordinates of jwk %s", k.Kid)
+		}
+		// ecdh rejects points not on the curve
+		if _, err = ec.NewPublicKey(append([]byte{4}, append(x, y...)...)); err != nil {
+			return nil, fmt.Errorf("invalid point of jwk %s: %w", k.Kid, err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s of jwk %s", k.Crv, k.Kid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key of jwk %s", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s of jwk %s", k.Kty, k.Kid)
+	}
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,277 @@
+package jwtx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/httpx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultClockSkew       = 60   // 1 minute
+	DefaultRefreshInterval = 3600 // 1 hour
+	DefaultUserIDClaim     = "sub"
+	DefaultUsernameClaim   = "preferred_username"
+)
+
+var (
+	ErrJwtExternalIssuer = errors.New("jwt tokens are issued by the external identity provider")
+
+	// minRefreshInterval limits the refreshes triggered by tokens of unknown kid
+	minRefreshInterval = 30 * time.Second
+)
+
+// OIDCConfig verifies the access tokens of an external identity provider instead of minting tokens
+type OIDCConfig struct {
+	Issuer          string        `mapstructure:"issuer"`           // Issuer validated against iss, enables the OIDC mode
+	DiscoveryURL    string        `mapstructure:"discovery_url"`    // Discovery document URL, default issuer/.well-known/openid-configuration
+	Audience        []string      `mapstructure:"audience"`         // Accepted audiences, a token must have one of them in aud
+	ClockSkew       int64         `mapstructure:"clock_skew"`       // Leeway in seconds of exp, nbf and iat
+	RefreshInterval int64         `mapstructure:"refresh_interval"` // Seconds the keys are cached before being fetched again
+	Claims          ClaimsMapping `mapstructure:"claims"`           // Paths of the provider claims mapped onto Claims
+}
+
+// ClaimsMapping maps provider claims onto Claims, paths are dot separated like realm_access.roles
+type ClaimsMapping struct {
+	UserID   string            `mapstructure:"user_id"`  // Claim of Claims.UserID, default sub
+	Username string            `mapstructure:"username"` // Claim of Claims.Username, default preferred_username
+	Data     map[string]string `mapstructure:"data"`     // Claims of Claims.Data by key
+}
+
+// oidcProvider caches the keys of the JWKS discovered from the issuer
+type oidcProvider struct {
+	config OIDCConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	mu      sync.RWMutex
+	jwksURI string
+	keys    map[string]*verifyKey
+	// fetchedAt is the time of the last fetch, attemptedAt of the last attempt throttled by minRefreshInterval
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshMu   sync.Mutex
+}
+
+func newOIDCProvider(cfg OIDCConfig) *oidcProvider {
+	if cfg.DiscoveryURL == "" {
+		cfg.DiscoveryURL = strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+	if cfg.ClockSkew == 0 {
+		cfg.ClockSkew = DefaultClockSkew
+	}
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+	if cfg.Claims.UserID == "" {
+		cfg.Claims.UserID = DefaultUserIDClaim
+	}
+	if cfg.Claims.Username == "" {
+		cfg.Claims.Username = DefaultUsernameClaim
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithLeeway(time.Duration(cfg.ClockSkew) * time.Second),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithJSONNumber(),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audience...))
+	}
+
+	return &oidcProvider{
+		config: cfg,
+		client: httpx.NewClient(httpx.Config{Name: "oidc", Timeout: 5000}),
+		parser: jwt.NewParser(opts...),
+	}
+}
+
+// parse verifies the token by the keys of the issuer and maps its claims
+func (p *oidcProvider) parse(tokenString string) (*Claims, error) {
+	mc := jwt.MapClaims{}
+	_, err := p.parser.ParseWithClaims(tokenString, mc, p.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	return p.claims(mc), nil
+}
+
+func (p *oidcProvider) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := p.key(kid)
+	if !ok {
+		return nil, ErrJwtUnknownKey
+	}
+	if key.method != nil && token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return key.public, nil
+}
+
+// key returns the key of the kid, fetching the keys when they are stale or the kid is unknown,
+// e.g. after the provider rotated its keys
+func (p *oidcProvider) key(kid string) (*verifyKey, bool) {
+	p.mu.RLock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.fetchedAt) > time.Duration(p.config.RefreshInterval)*time.Second
+	throttled := time.Since(p.attemptedAt) < minRefreshInterval
+	p.mu.RUnlock()
+
+	if ok && !stale || throttled {
+		return key, ok
+	}
+
+	if err := p.refresh(context.Background()); err != nil {
+		logger.Warnf("Failed to refresh the JWKS of %s: %v", p.config.Issuer, err)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	key, ok = p.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the keys, concurrent calls wait for the running fetch instead of fetching again,
+// the cached keys are kept when the fetch fails
+func (p *oidcProvider) refresh(ctx context.Context) error {
+	p.mu.RLock()
+	attemptedAt := p.attemptedAt
+	p.mu.RUnlock()
+
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	p.mu.Lock()
+	if p.attemptedAt.After(attemptedAt) {
+		p.mu.Unlock()
+		return nil
+	}
+	p.attemptedAt = time.Now()
+	jwksURI := p.jwksURI
+	p.mu.Unlock()
+
+	if jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := p.get(ctx, p.config.DiscoveryURL, &discovery); err != nil {
+			return err
+		}
+		if discovery.Issuer != p.config.Issuer {
+			return fmt.Errorf("issuer %s of the discovery document mismatches %s", discovery.Issuer, p.config.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return errors.New("no jwks_uri in the discovery document")
+		}
+		jwksURI = discovery.JWKSURI
+	}
+
+	var set JWKS
+	if err := p.get(ctx, jwksURI, &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*verifyKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		public, err := jwk.PublicKey()
+		if err != nil {
+			logger.Debugf("Skip jwk %s of %s: %v", jwk.Kid, p.config.Issuer, err)
+			continue
+		}
+		keys[jwk.Kid] = &verifyKey{kid: jwk.Kid, method: jwt.GetSigningMethod(jwk.Alg), public: public}
+	}
+
+	p.mu.Lock()
+	p.jwksURI = jwksURI
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	p.mu.Unlock()
+
+	logger.Debugf("Fetched %d keys of %s", len(keys), p.config.Issuer)
+	return nil
+}
+
+func (p *oidcProvider) get(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d of %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// claims maps the provider claims onto Claims
+func (p *oidcProvider) claims(mc jwt.MapClaims) *Claims {
+	claims := &Claims{
+		UserID:   claimString(lookupClaim(mc, p.config.Claims.UserID)),
+		Username: claimString(lookupClaim(mc, p.config.Claims.Username)),
+	}
+	for key, path := range p.config.Claims.Data {
+		if value, ok := lookupClaim(mc, path); ok {
+			if claims.Data == nil {
+				claims.Data = make(map[string]interface{})
+			}
+			claims.Data[key] = value
+		}
+	}
+
+	claims.Issuer, _ = mc.GetIssuer()
+	claims.Subject, _ = mc.GetSubject()
+	claims.Audience, _ = mc.GetAudience()
+	claims.ExpiresAt, _ = mc.GetExpirationTime()
+	claims.NotBefore, _ = mc.GetNotBefore()
+	claims.IssuedAt, _ = mc.GetIssuedAt()
+	claims.ID, _ = mc["jti"].(string)
+	return claims
+}
+
+// lookupClaim finds a claim by its name, or by its dot separated path into nested objects
+func lookupClaim(mc jwt.MapClaims, path string) (any, bool) {
+	if value, ok := mc[path]; ok {
+		return value, true
+	}
+
+	var current any = map[string]any(mc)
+	for _, name := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func claimString(value any, ok bool) string {
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
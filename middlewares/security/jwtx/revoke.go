@@ -0,0 +1,267 @@
+package jwtx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gfa-inc/gfa/common/cache/redisx"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultRefreshExpireTime = 604800 // 7 days
+	DefaultRevocationPrefix  = "jwt:"
+	TokenUseRefresh          = "refresh"
+)
+
+var (
+	ErrJwtRevoked            = errors.New("jwt token revoked")
+	ErrJwtRefreshReused      = errors.New("jwt refresh token reused")
+	ErrJwtRevocationDisabled = errors.New("jwt revocation not enabled")
+	ErrJwtRedisNotConfig     = errors.New("redis client not configured")
+)
+
+// rotateScript swaps the current refresh token of a family, a token other than the current one
+// is a reuse and revokes the family
+var rotateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+if current ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+return 1
+`)
+
+// RevocationConfig keeps the revoked tokens and the refresh token families in redis
+type RevocationConfig struct {
+	Enable            bool   `mapstructure:"enable"`              // Enable revocation and refresh tokens
+	Redis             string `mapstructure:"redis"`               // Name of the redisx client, default the default client
+	Prefix            string `mapstructure:"prefix"`              // Redis key prefix
+	RefreshExpireTime int64  `mapstructure:"refresh_expire_time"` // Refresh token expiration time in seconds
+}
+
+// TokenPair is the access token and the refresh token of a login
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+func (j *Validator) setupRevocation(redisClient ...redis.UniversalClient) {
+	if j.config.Revocation.Prefix == "" {
+		j.config.Revocation.Prefix = DefaultRevocationPrefix
+	}
+	if j.config.Revocation.RefreshExpireTime == 0 {
+		j.config.Revocation.RefreshExpireTime = DefaultRefreshExpireTime
+	}
+
+	switch {
+	case len(redisClient) > 0:
+		j.redisClient = redisClient[0]
+	case j.config.Revocation.Redis != "":
+		j.redisClient = redisx.GetClient(j.config.Revocation.Redis)
+	default:
+		j.redisClient = redisx.Client
+	}
+	if j.redisClient == nil {
+		logger.Panic(ErrJwtRedisNotConfig)
+	}
+}
+
+func (j *Validator) revokedKey(jti string) string {
+	return j.config.Revocation.Prefix + "revoked:" + jti
+}
+
+func (j *Validator) userKey(userID string) string {
+	return j.config.Revocation.Prefix + "user:" + userID
+}
+
+func (j *Validator) familyKey(sid string) string {
+	return j.config.Revocation.Prefix + "family:" + sid
+}
+
+// checkRevoked rejects the tokens in the denylist, of revoked families, or issued before the sessions
+// of their user were revoked, redis errors reject the token too
+func (j *Validator) checkRevoked(ctx context.Context, claims *Claims) error {
+	if j.redisClient == nil {
+		return nil
+	}
+
+	var (
+		revokedAt       *redis.StringCmd
+		denied, current *redis.IntCmd
+	)
+	// the keys may be in different slots of a cluster, so they are read by a pipeline instead of MGET
+	_, err := j.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		revokedAt = pipe.Get(ctx, j.userKey(claims.UserID))
+		if claims.ID != "" {
+			denied = pipe.Exists(ctx, j.revokedKey(claims.ID))
+		}
+		if claims.SessionID != "" {
+			current = pipe.Exists(ctx, j.familyKey(claims.SessionID))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logger.Errorf("Failed to check JWT revocation: %v", err)
+		return err
+	}
+
+	if at, err := revokedAt.Int64(); err == nil && issuedAtNano(claims) <= at {
+		return ErrJwtRevoked
+	}
+	if denied != nil && denied.Val() > 0 {
+		return ErrJwtRevoked
+	}
+	if current != nil && current.Val() == 0 {
+		return ErrJwtRevoked
+	}
+	return nil
+}
+
+// issuedAtNano returns the issue time of a token in nanoseconds, tokens without iat_ns count from the start
+// of their second, so they are rejected when the sessions are revoked in the same second
+func issuedAtNano(claims *Claims) int64 {
+	if claims.IssuedAtNano > 0 {
+		return claims.IssuedAtNano
+	}
+	if claims.IssuedAt != nil {
+		return claims.IssuedAt.Unix() * int64(time.Second)
+	}
+	return 0
+}
+
+// IssueTokens generates the access token and the refresh token of a new login, the refresh token is
+// exchanged by Refresh for a new pair once
+func (j *Validator) IssueTokens(ctx context.Context, userID, username string, data map[string]interface{}) (*TokenPair, error) {
+	if j.oidc != nil {
+		return nil, ErrJwtExternalIssuer
+	}
+	if j.redisClient == nil {
+		return nil, ErrJwtRevocationDisabled
+	}
+
+	sid := uuid.NewString()
+	pair, jti, err := j.issuePair(userID, username, data, sid)
+	if err != nil {
+		return nil, err
+	}
+	err = j.redisClient.Set(ctx, j.familyKey(sid), jti, time.Duration(j.config.Revocation.RefreshExpireTime)*time.Second).Err()
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh rotates a refresh token into a new pair, reusing a rotated refresh token revokes its family,
+// i.e. every token of the login
+func (j *Validator) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if j.oidc != nil {
+		return nil, ErrJwtExternalIssuer
+	}
+	if j.redisClient == nil {
+		return nil, ErrJwtRevocationDisabled
+	}
+
+	claims, err := j.ParseToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != TokenUseRefresh || claims.SessionID == "" {
+		return nil, ErrJwtInvalidToken
+	}
+	if err = j.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	pair, jti, err := j.issuePair(claims.UserID, claims.Username, claims.Data, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := rotateScript.Run(ctx, j.redisClient, []string{j.familyKey(claims.SessionID)},
+		claims.ID, jti, j.config.Revocation.RefreshExpireTime).Int()
+	if err != nil {
+		return nil, err
+	}
+	switch result {
+	case 0:
+		logger.Warnf("JWT refresh token reused, revoked the sessions of family %s of user %s", claims.SessionID, claims.UserID)
+		return nil, ErrJwtRefreshReused
+	case -1:
+		return nil, ErrJwtRevoked
+	}
+	return pair, nil
+}
+
+// issuePair signs the tokens of a family, returning the jti of the refresh token
+func (j *Validator) issuePair(userID, username string, data map[string]interface{}, sid string) (*TokenPair, string, error) {
+	access := j.newClaims(userID, username, data, j.config.ExpireTime)
+	access.SessionID = sid
+	accessToken, err := j.sign(access)
+	if err != nil {
+		return nil, "", err
+	}
+
+	refresh := j.newClaims(userID, username, data, j.config.Revocation.RefreshExpireTime)
+	refresh.SessionID = sid
+	refresh.TokenUse = TokenUseRefresh
+	refreshToken, err := j.sign(refresh)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    j.config.ExpireTime,
+	}, refresh.ID, nil
+}
+
+// Revoke denies the token until it expires, and revokes its family if it was issued by IssueTokens
+func (j *Validator) Revoke(ctx context.Context, claims *Claims) error {
+	if j.redisClient == nil {
+		return ErrJwtRevocationDisabled
+	}
+
+	expiration := time.Duration(j.config.ExpireTime) * time.Second
+	if claims.ExpiresAt != nil {
+		expiration = time.Until(claims.ExpiresAt.Time)
+	}
+	_, err := j.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if claims.ID != "" && expiration > 0 {
+			pipe.Set(ctx, j.revokedKey(claims.ID), 1, expiration)
+		}
+		if claims.SessionID != "" {
+			pipe.Del(ctx, j.familyKey(claims.SessionID))
+		}
+		return nil
+	})
+	return err
+}
+
+// RevokeAll revokes every token issued to the user so far
+func (j *Validator) RevokeAll(ctx context.Context, userID string) error {
+	if j.redisClient == nil {
+		return ErrJwtRevocationDisabled
+	}
+
+	expiration := max(j.config.ExpireTime, j.config.Revocation.RefreshExpireTime)
+	return j.redisClient.Set(ctx, j.userKey(userID), time.Now().UnixNano(), time.Duration(expiration)*time.Second).Err()
+}
+
+// Logout revokes the token of the request and its family
+func (j *Validator) Logout(c *gin.Context) error {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return ErrJwtInvalidToken
+	}
+	return j.Revoke(c.Request.Context(), claims)
+}
@@ -0,0 +1,250 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/middlewares/security/apikey"
+	"github.com/gfa-inc/gfa/middlewares/security/jwtx"
+	"github.com/gfa-inc/gfa/middlewares/security/session"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+const (
+	// MatchAny accepts the request once a validator of the policy passes
+	MatchAny = "any"
+	// MatchAll requires every validator of the policy to pass
+	MatchAll = "all"
+	// Types is the context key of the names of the validators authenticating the request
+	Types = "security_types"
+)
+
+var (
+	// ErrMissingCredentials is wrapped by the errors of custom validators finding no credentials in the request,
+	// the errors of validators rejecting the credentials found are reported first
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is wrapped by the errors of custom validators rejecting the credentials found,
+	// only the errors of the validators wrapping a known error are shown to the client
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNoValidator        = errors.New("no security validator configured")
+
+	// builtinOrder is the order of the builtin validators in the default policy
+	builtinOrder = []string{DefaultSessionValidatorName, DefaultJWTValidatorName, DefaultApiKeyValidatorName}
+	// missingCredentials are the errors of the builtin validators finding no credentials
+	missingCredentials = []error{
+		ErrMissingCredentials,
+		session.ErrNotFoundSession,
+		jwtx.ErrJwtNotFoundHeaderToken,
+		jwtx.ErrJwtNotFoundQueryToken,
+		apikey.ErrNotFoundApiKey,
+	}
+	// rejectedCredentials are the errors of the validators rejecting the credentials found
+	rejectedCredentials = []error{
+		ErrInvalidCredentials,
+		jwtx.ErrJwtInvalidToken,
+		jwtx.ErrJwtExpired,
+		jwtx.ErrJwtRevoked,
+		apikey.ErrApiKeyValidateFailed,
+	}
+)
+
+// Policy authenticates the requests of the routes matching Path and Methods by its validators in order
+type Policy struct {
+	// Path is a route like /admin/users/:id, or the routes under a prefix like /webhooks/*
+	Path string
+	// Methods matched, every method when empty
+	Methods []string
+	// Validators are the names of the validators tried in order
+	Validators []string
+	// Match is any or all, default any
+	Match string
+}
+
+var (
+	policies       []Policy
+	customPolicies []Policy
+	defaultPolicy  Policy
+)
+
+// WithPolicy adds a policy before the security middleware is created, policies of security.policies are added too,
+// the most specific policy matching a route applies, other routes are authenticated by any enabled validator
+func WithPolicy(p Policy) {
+	customPolicies = append(customPolicies, p)
+}
+
+func setupPolicies() {
+	var configured []Policy
+	if config.Get("security.policies") != nil {
+		err := config.UnmarshalKey("security.policies", &configured)
+		if err != nil {
+			logger.Panic(err)
+		}
+	}
+
+	basePath := config.GetString("server.base_path")
+	policies = make([]Policy, 0, len(configured)+len(customPolicies))
+	for _, p := range append(configured, customPolicies...) {
+		p.Path = withBasePath(p.Path, basePath)
+		if err := validatePolicy(&p); err != nil {
+			logger.Panic(err)
+		}
+		policies = append(policies, p)
+	}
+	// more specific policies first, policies equally specific keep the order they are added in
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].specificity() > policies[j].specificity()
+	})
+
+	defaultPolicy = Policy{Match: MatchAny}
+	for _, name := range builtinOrder {
+		if _, ok := validators[name]; ok {
+			defaultPolicy.Validators = append(defaultPolicy.Validators, name)
+		}
+	}
+	custom := lo.Filter(lo.Keys(validators), func(name string, _ int) bool {
+		return !slices.Contains(builtinOrder, name)
+	})
+	slices.Sort(custom)
+	defaultPolicy.Validators = append(defaultPolicy.Validators, custom...)
+
+	for _, p := range policies {
+		logger.Debugf("Security policy %s %v: %s of %s", p.Path, p.Methods, p.Match, strings.Join(p.Validators, ", "))
+	}
+}
+
+// withBasePath prefixes the path of a policy with the base path, unless its first segments are the base path,
+// e.g. /api/users is kept but /apikeys/* becomes /api/apikeys/* with the base path /api
+func withBasePath(path, basePath string) string {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" || path == basePath || strings.HasPrefix(path, basePath+"/") {
+		return path
+	}
+	return basePath + path
+}
+
+func validatePolicy(p *Policy) error {
+	if p.Path == "" {
+		return errors.New("path of security policy required")
+	}
+	if len(p.Validators) == 0 {
+		return fmt.Errorf("no validators of security policy %s", p.Path)
+	}
+	for _, name := range p.Validators {
+		if _, ok := validators[name]; !ok {
+			return fmt.Errorf("validator %s of security policy %s not enabled", name, p.Path)
+		}
+	}
+
+	switch strings.ToLower(p.Match) {
+	case "", MatchAny:
+		p.Match = MatchAny
+	case MatchAll:
+		p.Match = MatchAll
+	default:
+		return fmt.Errorf("invalid match %s of security policy %s", p.Match, p.Path)
+	}
+	return nil
+}
+
+// specificity ranks exact routes over prefixes, longer prefixes over shorter ones, and policies of methods over the others
+func (p *Policy) specificity() int {
+	rank := len(p.Path) * 2
+	if !strings.HasSuffix(p.Path, "/*") {
+		rank += 1 << 16
+	}
+	if len(p.Methods) > 0 {
+		rank++
+	}
+	return rank
+}
+
+func (p *Policy) match(route, method string) bool {
+	if len(p.Methods) > 0 && !slices.ContainsFunc(p.Methods, func(m string) bool {
+		return strings.EqualFold(m, method)
+	}) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(p.Path, "/*"); ok {
+		return route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+	return route == p.Path
+}
+
+// matchPolicy returns the policy of a route, routes not found are matched by their path
+func matchPolicy(c *gin.Context) *Policy {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return policyOf(route, c.Request.Method)
+}
+
+func policyOf(route, method string) *Policy {
+	for i := range policies {
+		if policies[i].match(route, method) {
+			return &policies[i]
+		}
+	}
+	return &defaultPolicy
+}
+
+// RoutePolicy returns the names of the validators authenticating a route and whether any or all of them must pass,
+// e.g. to document the API
+func RoutePolicy(route, method string) ([]string, string) {
+	p := policyOf(route, method)
+	return p.Validators, p.Match
+}
+
+// authenticate runs the validators of the policy in order, returning the names of the validators passing,
+// or the error of the most relevant validator failing
+func (p *Policy) authenticate(c *gin.Context) ([]string, error) {
+	if len(p.Validators) == 0 {
+		return nil, ErrNoValidator
+	}
+
+	var (
+		passed   []string
+		relevant error
+	)
+	for _, name := range p.Validators {
+		err := validators[name].Valid(c)
+		if p.Match == MatchAll {
+			if err != nil {
+				return nil, err
+			}
+			passed = append(passed, name)
+			continue
+		}
+
+		if err == nil {
+			return []string{name}, nil
+		}
+		if relevant == nil || isMissingCredentials(relevant) && !isMissingCredentials(err) {
+			relevant = err
+		}
+	}
+	return passed, relevant
+}
+
+func isMissingCredentials(err error) bool {
+	return slices.ContainsFunc(missingCredentials, func(target error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// clientReason returns the reason of a failed authentication shown to the client, nil for errors not known
+// to be about the credentials, e.g. of redis, which are logged only
+func clientReason(err error) error {
+	if isMissingCredentials(err) || slices.ContainsFunc(rejectedCredentials, func(target error) bool {
+		return errors.Is(err, target)
+	}) {
+		return err
+	}
+	return nil
+}
@@ -7,12 +7,15 @@ import (
 
 	"github.com/gfa-inc/gfa/common/config"
 	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares/accesslog"
 	"github.com/gfa-inc/gfa/middlewares/security/apikey"
 	"github.com/gfa-inc/gfa/middlewares/security/jwtx"
 	"github.com/gfa-inc/gfa/middlewares/security/session"
+	sessionmw "github.com/gfa-inc/gfa/middlewares/session"
+	"github.com/gfa-inc/gfa/utils/httpmethod"
 	"github.com/gfa-inc/gfa/utils/router"
 	"github.com/gin-gonic/gin"
-	"github.com/samber/lo"
 )
 
 type Validator interface {
@@ -82,8 +85,9 @@ func Security() gin.HandlerFunc {
 	for k, v := range customValidators {
 		validators[k] = v
 	}
+	setupPolicies()
 
-	logger.Debugf("Enabled security validators: %s", strings.Join(lo.Keys(validators), ", "))
+	logger.Debugf("Enabled security validators: %s", strings.Join(defaultPolicy.Validators, ", "))
 	logger.Info("Security middleware enabled")
 
 	return func(c *gin.Context) {
@@ -100,26 +104,25 @@ func Security() gin.HandlerFunc {
 			return
 		}
 
-		if validators == nil {
+		names, err := matchPolicy(c).authenticate(c)
+		if err == nil {
+			c.Set(Type, names[0])
+			c.Set(Types, names)
 			c.Next()
 			return
 		}
 
-		for k, v := range validators {
-			if v.Valid(c) == nil {
-				c.Set(Type, k)
-				c.Next()
-				return
-			}
-		}
-
-		logger.Warnf("Unauthorized access attempt - path=%s method=%s ip=%s ua=%s",
+		logger.Warnf("Unauthorized access attempt - path=%s method=%s ip=%s ua=%s err=%v",
 			c.FullPath(),
 			c.Request.Method,
 			c.ClientIP(),
-			c.Request.UserAgent())
+			c.Request.UserAgent(),
+			err)
 
-		c.AbortWithStatus(http.StatusUnauthorized)
+		// the onerror middleware responds the reason, the status stands without it
+		_ = c.Error(core.NewUnauthorizedErr().WithErr(clientReason(err)))
+		c.Status(http.StatusUnauthorized)
+		c.Abort()
 	}
 }
 
@@ -143,6 +146,70 @@ func PermitRoutes(routes [][]any) {
 	}
 }
 
+// Setup mounts the JWKS endpoint of the jwt validator when security.jwt.jwks_path is configured
+func Setup(r *gin.RouterGroup) {
+	jv, ok := validators[DefaultJWTValidatorName].(*jwtx.Validator)
+	if !ok || jv.JWKSPath() == "" {
+		return
+	}
+
+	path := jv.JWKSPath()
+	r.GET(path, jv.JWKSHandler())
+	PermitRoute(path, httpmethod.MethodGet)
+	accesslog.PermitRoute(path, httpmethod.MethodGet)
+
+	logger.Infof("JWKS endpoint enabled, path: %s", path)
+}
+
+// RequiresAuth reports whether the security middleware validates requests of a route
+func RequiresAuth(route, method string) bool {
+	if matcher == nil {
+		return false
+	}
+	if apiPrefix != "" && !strings.HasPrefix(route, apiPrefix) {
+		return false
+	}
+	return !matcher.Match(route, method)
+}
+
+// Scheme describes where a validator reads credentials from, e.g. to document the API
+type Scheme struct {
+	// Type is http or apiKey
+	Type string
+	// Scheme is the HTTP authorization scheme of http type, e.g. bearer
+	Scheme       string
+	BearerFormat string
+	// In is header, query or cookie for apiKey type
+	In   string
+	Name string
+}
+
+// Schemes returns the credential locations of the enabled validators keyed by validator name,
+// custom validators are not described
+func Schemes() map[string][]Scheme {
+	schemes := make(map[string][]Scheme)
+	for name, v := range validators {
+		switch validator := v.(type) {
+		case *jwtx.Validator:
+			for _, lookup := range validator.Lookups() {
+				in, key := lookup[0], strings.TrimSpace(lookup[1])
+				if in == "header" && strings.EqualFold(key, "Authorization") {
+					schemes[name] = append(schemes[name], Scheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+					continue
+				}
+				schemes[name] = append(schemes[name], Scheme{Type: "apiKey", In: in, Name: key})
+			}
+		case *apikey.Validator:
+			for _, lookup := range validator.Lookups() {
+				schemes[name] = append(schemes[name], Scheme{Type: "apiKey", In: lookup[0], Name: strings.TrimSpace(lookup[1])})
+			}
+		case *session.Validator:
+			schemes[name] = append(schemes[name], Scheme{Type: "apiKey", In: "cookie", Name: sessionmw.CookieName})
+		}
+	}
+	return schemes
+}
+
 func IsPermitted(c *gin.Context) bool {
 	return c.GetBool(PermittedFlag)
 }
@@ -156,3 +223,9 @@ func SetSession(c *gin.Context, value any) error {
 	sessionValidator := v.(*session.Validator)
 	return sessionValidator.Set(c, value)
 }
+
+// JWTValidator returns the validator of security.jwt, nil if not configured
+func JWTValidator() *jwtx.Validator {
+	jv, _ := validators[DefaultJWTValidatorName].(*jwtx.Validator)
+	return jv
+}
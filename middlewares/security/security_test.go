@@ -0,0 +1,113 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func headerValidator(header, value string) Validator {
+	return NewValidator(func(c *gin.Context) error {
+		switch c.GetHeader(header) {
+		case "":
+			return fmt.Errorf("%w: %s", ErrMissingCredentials, header)
+		case value:
+			return nil
+		case "error":
+			return errors.New("dial tcp 10.0.0.1:6379: connection refused")
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidCredentials, header)
+		}
+	})
+}
+
+func TestSecurityPolicies(t *testing.T) {
+	config.Setup()
+	logger.Setup()
+	core.SetupResponse()
+	config.SetDefault("security.policies", []map[string]any{
+		{"path": "/admin/*", "validators": []string{"token", "key"}, "match": "all"},
+		{"path": "/admin/health", "methods": []string{"GET"}, "validators": []string{"token"}},
+	})
+	WithValidator("token", headerValidator("X-Token", "t"))
+	WithValidator("key", headerValidator("X-Key", "k"))
+	WithPolicy(Policy{Path: "/webhooks/*", Validators: []string{"key"}})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middlewares.OnError(), Security())
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, c.GetStringSlice(Types))
+	}
+	engine.GET("/orders", handler)
+	engine.GET("/admin/users", handler)
+	engine.GET("/admin/health", handler)
+	engine.POST("/webhooks/pay", handler)
+	assert.Equal(t, []string{"key", "token"}, defaultPolicy.Validators)
+
+	request := func(method, path string, headers map[string]string) (int, string) {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code == http.StatusOK || w.Body.Len() == 0 {
+			return w.Code, w.Body.String()
+		}
+		var resp core.Response[any]
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp.Message
+	}
+
+	token, key := map[string]string{"X-Token": "t"}, map[string]string{"X-Key": "k"}
+	both := map[string]string{"X-Token": "t", "X-Key": "k"}
+
+	code, body := request(http.MethodGet, "/orders", token)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `["token"]`, body)
+	// the validator rejecting its credentials is reported over the one missing them
+	code, body = request(http.MethodGet, "/orders", map[string]string{"X-Token": "x"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "invalid credentials: X-Token", body)
+	// other errors are not shown
+	code, body = request(http.MethodGet, "/orders", map[string]string{"X-Token": "error"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "", body)
+	code, body = request(http.MethodGet, "/orders", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "missing credentials: X-Key", body)
+
+	code, body = request(http.MethodGet, "/admin/users", token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "missing credentials: X-Key", body)
+	code, body = request(http.MethodGet, "/admin/users", both)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `["token","key"]`, body)
+	code, _ = request(http.MethodGet, "/admin/health", token)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = request(http.MethodPost, "/webhooks/pay", token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, body = request(http.MethodPost, "/webhooks/pay", key)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `["key"]`, body)
+}
+
+func TestWithBasePath(t *testing.T) {
+	assert.Equal(t, "/api/users", withBasePath("/api/users", "/api"))
+	assert.Equal(t, "/api", withBasePath("/api", "/api/"))
+	assert.Equal(t, "/api/apikeys/*", withBasePath("/apikeys/*", "/api"))
+	assert.Equal(t, "/api/api-docs", withBasePath("/api-docs", "/api"))
+	assert.Equal(t, "/users", withBasePath("/users", ""))
+}
@@ -13,6 +13,9 @@ import (
 
 var DefaultTimeout = 86400
 
+// CookieName of the session ID
+const CookieName = "_SESSIONID"
+
 type Config struct {
 	PrivateKey string
 	MaxAge     int
@@ -66,7 +69,7 @@ func Session() gin.HandlerFunc {
 	store.SetSerializer(redistore.JSONSerializer{})
 
 	logger.Info("Session middleware enabled")
-	return sessions.Sessions("_SESSIONID", newRedisStore)
+	return sessions.Sessions(CookieName, newRedisStore)
 }
 
 func Enabled() bool {
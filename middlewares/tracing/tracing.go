@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Tracing extracts the W3C trace context and baggage of the request, starts a server span
+// named after the matched route and writes the traceparent of the span back on the response,
+// the sampled flag and tracestate of the remote parent are kept
+func Tracing() gin.HandlerFunc {
+	logger.Info("Tracing middleware enabled")
+	return func(c *gin.Context) {
+		ctx := logger.Propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		spanName := c.FullPath()
+		if spanName == "" {
+			spanName = "HTTP " + c.Request.Method
+		}
+		ctx, span := logger.StartSpan(ctx, spanName,
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			))
+		defer span.End()
+		if route := c.FullPath(); route != "" {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		// expose the span to the loggers using the gin context, the request ID is read by requestid.Get
+		spanCtx := span.SpanContext()
+		if spanCtx.IsValid() {
+			c.Set(logger.TraceIDKey, spanCtx.TraceID().String())
+			c.Set(logger.SpanIDKey, spanCtx.SpanID().String())
+		}
+		c.Set(logger.SpanNameKey, spanName)
+
+		// headers must be written before the handlers flush the response
+		logger.Propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		if len(c.Errors) > 0 {
+			span.SetStatus(codes.Error, c.Errors.Last().Error())
+		} else if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gfa-inc/gfa/common/config"
+	"github.com/gfa-inc/gfa/common/logger"
+	"github.com/gfa-inc/gfa/core"
+	"github.com/gfa-inc/gfa/middlewares"
+	"github.com/gfa-inc/gfa/middlewares/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	parentTraceID = "0af7651916cd43dd8448eb211c80319c"
+	parentSpanID  = "b7ad6b7169203331"
+)
+
+func TestTracing(t *testing.T) {
+	config.Setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+		option.Tracing = &logger.TracingConfig{
+			Enabled:  true,
+			Exporter: &logger.ExporterConfig{Type: logger.ExporterMemory},
+		}
+	})
+	logger.MemoryExporter().Reset()
+
+	engine := gin.New()
+	engine.Use(requestid.RequestID(), Tracing(), middlewares.OnError())
+	engine.GET("/users/:id", func(c *gin.Context) {
+		member := baggage.FromContext(c.Request.Context()).Member("tenant")
+		core.OK(c, member.Value())
+	})
+	engine.GET("/ids", func(c *gin.Context) {
+		c.String(http.StatusOK, requestid.Get(c)+" "+logger.GetTraceID(c))
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(errors.New("boom"))
+	})
+	engine.GET("/param", func(c *gin.Context) {
+		_ = c.Error(core.NewParamErr("id is required"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	req.Header.Set("tracestate", "vendor=abc")
+	req.Header.Set("baggage", "tenant=acme")
+	engine.ServeHTTP(w, req)
+
+	var resp core.Response[string]
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, parentTraceID, resp.TraceID)
+	assert.Equal(t, "acme", resp.Data)
+	assert.True(t, strings.HasPrefix(w.Header().Get("traceparent"), "00-"+parentTraceID+"-"))
+	assert.Equal(t, "vendor=abc", w.Header().Get("tracestate"))
+
+	spans := logger.MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/users/:id", spans[0].Name)
+	assert.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind)
+	assert.Equal(t, parentSpanID, spans[0].Parent.SpanID().String())
+	assert.True(t, spans[0].Parent.IsRemote())
+	assert.Equal(t, "vendor=abc", spans[0].SpanContext.TraceState().String())
+
+	// the request ID is kept apart from the trace ID
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/ids", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	req.Header.Set("X-Request-ID", "req-1")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, "req-1 "+parentTraceID, w.Body.String())
+	assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"))
+
+	// a malformed traceparent starts a new trace
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/ids", nil)
+	req.Header.Set("traceparent", "00-xyz-"+parentSpanID+"-01")
+	engine.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), parentTraceID)
+	assert.NotEmpty(t, w.Header().Get("traceparent"))
+
+	logger.MemoryExporter().Reset()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans = logger.MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "boom", spans[0].Status.Description)
+	assert.Len(t, spans[0].Events, 1)
+	assert.NotEmpty(t, w.Header().Get("traceparent"))
+
+	// the status written by OnError is recorded
+	logger.MemoryExporter().Reset()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/param", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	spans = logger.MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes, semconv.HTTPResponseStatusCode(http.StatusBadRequest))
+
+	_ = logger.ShutdownTracing(t.Context())
+}
+
+func TestTracingUnsampledParent(t *testing.T) {
+	config.Setup()
+	logger.Setup(func(option *logger.Config) {
+		option.Level = "info"
+		option.Tracing = &logger.TracingConfig{
+			Enabled:  true,
+			Exporter: &logger.ExporterConfig{Type: logger.ExporterMemory},
+			Sampler:  &logger.SamplerConfig{Type: logger.SamplerAlwaysOff, ParentBased: true},
+		}
+	})
+	defer func() {
+		_ = logger.ShutdownTracing(t.Context())
+	}()
+	logger.MemoryExporter().Reset()
+
+	engine := gin.New()
+	engine.Use(Tracing())
+	engine.GET("/users/:id", func(c *gin.Context) {})
+
+	// the decision of a parent not sampled is kept and propagated
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-00")
+	engine.ServeHTTP(w, req)
+	assert.Empty(t, logger.MemoryExporter().GetSpans())
+	assert.True(t, strings.HasPrefix(w.Header().Get("traceparent"), "00-"+parentTraceID+"-"))
+	assert.True(t, strings.HasSuffix(w.Header().Get("traceparent"), "-00"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	engine.ServeHTTP(w, req)
+	assert.Len(t, logger.MemoryExporter().GetSpans(), 1)
+	assert.True(t, strings.HasSuffix(w.Header().Get("traceparent"), "-01"))
+}
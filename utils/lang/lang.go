@@ -0,0 +1,71 @@
+// Package lang carries the locale of a request from the locale middleware to core and the handlers,
+// which read it without depending on the middleware
+package lang
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ContextKey is the key of the locale set on the gin.Context by the locale middleware
+const ContextKey = "locale"
+
+type localeKey struct{}
+
+// WithLocale sets the locale of a context, e.g. the request context
+func WithLocale(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, localeKey{}, tag)
+}
+
+// Get returns the locale set by WithLocale, or set on the gin.Context under ContextKey, empty if none
+func Get(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if tag, ok := ctx.Value(localeKey{}).(string); ok {
+		return tag
+	}
+	tag, _ := ctx.Value(ContextKey).(string)
+	return tag
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header ordered by quality
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+	return result
+}
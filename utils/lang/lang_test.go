@@ -0,0 +1,19 @@
+package lang
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"en-US", "en", "zh"}, ParseAcceptLanguage("zh;q=0.5, en-US, en;q=0.8, *;q=0.1"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestGet(t *testing.T) {
+	assert.Equal(t, "", Get(context.Background()))
+	assert.Equal(t, "en", Get(WithLocale(context.Background(), "en")))
+	assert.Equal(t, "zh", Get(context.WithValue(context.Background(), ContextKey, "zh")))
+}
@@ -0,0 +1,28 @@
+// Package principal carries the authenticated user from the security validators to the layers below,
+// which read it without depending on the validators
+package principal
+
+import "context"
+
+// UserIDKey is the key of the user ID set on the gin.Context by the security validators,
+// custom validators set it too to fill the audit columns
+const UserIDKey = "principal_user_id"
+
+type userIDKey struct{}
+
+// WithUserID sets the user of a context, e.g. for jobs running without a request
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserID returns the user set by WithUserID, or set on the gin.Context under UserIDKey
+func UserID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if userID, ok := ctx.Value(userIDKey{}).(string); ok {
+		return userID
+	}
+	userID, _ := ctx.Value(UserIDKey).(string)
+	return userID
+}